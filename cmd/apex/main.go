@@ -1,17 +1,23 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/apex/pkg/api/jsonrpc"
 	"github.com/apex/pkg/consensus"
 	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/genesis"
+	"github.com/apex/pkg/mempool"
+	"github.com/apex/pkg/network"
 	"github.com/apex/pkg/storage"
-	"github.com/apex/pkg/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -82,28 +88,91 @@ func runNode(cmd *cobra.Command, args []string) {
 	defer db.Close()
 	
 	// Initialize state DB
-	stateDB := storage.NewStateDB(db)
+	stateDB, err := storage.NewStateDB(db)
+	if err != nil {
+		logger.Fatal("Failed to open state", zap.Error(err))
+	}
 	
 	// Initialize block store
 	blockStore := storage.NewBlockStore(db)
 	
-	// Initialize DPoS consensus
-	dpos := consensus.NewDPoS()
+	// Load genesis
+	genesisPath := viper.GetString("node.genesis_file")
+	if genesisPath == "" {
+		genesisPath = "./config/genesis.json"
+	}
+	
+	gen, err := genesis.Load(genesisPath)
+	if err != nil {
+		logger.Fatal("Failed to load genesis", zap.Error(err))
+	}
+	if chainID := viper.GetString("node.chain_id"); chainID != "" && chainID != gen.ChainID {
+		logger.Fatal("Configured chain id does not match genesis",
+			zap.String("config", chainID),
+			zap.String("genesis", gen.ChainID),
+		)
+	}
+	
+	// Initialize DPoS consensus on top of the state database
+	dpos := consensus.NewDPoS(stateDB)
 	
 	// Initialize blockchain
-	blockchain := core.NewBlockchain(stateDB, blockStore, dpos)
-	
-	// Check if genesis exists
-	height := blockchain.GetHeight()
-	if height == 0 {
-		logger.Info("Initializing genesis block")
-		if err := initGenesis(blockchain); err != nil {
-			logger.Fatal("Failed to initialize genesis", zap.Error(err))
-		}
+	blockchain, err := core.NewBlockchain(gen.ChainID, stateDB, blockStore, dpos)
+	if err != nil {
+		logger.Fatal("Failed to load blockchain", zap.Error(err))
+	}
+	
+	// Initialize genesis on a new database, or check the stored one
+	if err := gen.InitChain(blockchain); err != nil {
+		logger.Fatal("Failed to initialize genesis", zap.Error(err))
 	}
 	
+	logger.Info("Genesis loaded",
+		zap.String("chain_id", gen.ChainID),
+		zap.String("genesis_hash", blockchain.GetGenesisHash().Hex()),
+	)
+	
 	logger.Info("Blockchain initialized", zap.Uint64("height", blockchain.GetHeight()))
 	
+	// Initialize the transaction pool, ordered by the base fee of the next
+	// block, emptied of the transactions new blocks include and refilled
+	// with those a reorg drops
+	mempoolSize := viper.GetInt("mempool.max_size")
+	if mempoolSize == 0 {
+		mempoolSize = 10000
+	}
+	pool := mempool.NewMempool(mempoolSize, gen.ChainID)
+	pool.FollowChain(blockchain)
+	blockchain.SetTxPool(pool)
+	
+	// Join the peer-to-peer network
+	p2p, err := network.NewP2PNetwork(
+		viper.GetString("network.listen_address"),
+		viper.GetStringSlice("network.bootstrap_nodes"),
+		logger,
+	)
+	if err != nil {
+		logger.Fatal("Failed to start p2p network", zap.Error(err))
+	}
+	defer p2p.Close()
+	
+	protocol := network.NewProtocol(blockchain, p2p, logger)
+	p2p.SetStreamHandler(protocol.HandleStream)
+	
+	// A validator precommits every new head so that blocks get finalized
+	if viper.GetBool("validator.enabled") {
+		key, err := loadValidatorKey(viper.GetString("validator.key_file"))
+		if err != nil {
+			logger.Fatal("Failed to load validator key", zap.Error(err))
+		}
+		blockchain.SubscribeNewHead(func(head *core.Block) {
+			if err := protocol.PrecommitBlock(head, key); err != nil {
+				logger.Debug("Did not precommit block", zap.Uint64("number", head.Header.Number), zap.Error(err))
+			}
+		})
+		logger.Info("Validator enabled", zap.String("address", crypto.PublicKeyToAddress(&key.PublicKey).Hex()))
+	}
+	
 	// Start JSON-RPC server
 	rpcPort := viper.GetInt("rpc.port")
 	if rpcPort == 0 {
@@ -127,24 +196,16 @@ func runNode(cmd *cobra.Command, args []string) {
 	logger.Info("Shutting down Apex node")
 }
 
-func initGenesis(blockchain *core.Blockchain) error {
-	// Create genesis validators
-	genesisValidators := make([]*types.Validator, 0)
-	
-	// You would load these from genesis.json in production
-	// For now, create a default validator
-	
-	// Create genesis accounts with initial distribution
-	totalSupply := types.ToWei(float64(types.TotalSupply))
-	genesisAccounts := []*types.Account{
-		{
-			Address: types.HexToAddress("0x0000000000000000000000000000000000000001"),
-			Balance: totalSupply,
-			Nonce:   0,
-			Staked:  types.ToWei(0),
-			Locked:  types.ToWei(0),
-		},
-	}
-	
-	return blockchain.InitGenesis(genesisValidators, genesisAccounts)
+// loadValidatorKey reads the private key from a key file written by
+// apexctl keys generate
+func loadValidatorKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keyData map[string]string
+	if err := json.Unmarshal(data, &keyData); err != nil {
+		return nil, err
+	}
+	return crypto.HexToPrivateKey(strings.TrimPrefix(keyData["private_key"], "0x"))
 }
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/types"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -82,9 +88,15 @@ func validatorCmd() *cobra.Command {
 
 	unjailCmd := &cobra.Command{
 		Use:   "unjail",
-		Short: "Unjail a validator",
-		Run:   runValidatorUnjail,
+		Short: "Sign a transaction releasing a validator from jail",
+		Long: "Sign an unjail transaction with the validator key. The chain accepts it once the\n" +
+			"jail period is over, unless the validator was tombstoned for double signing.",
+		Run: runValidatorUnjail,
 	}
+	unjailCmd.Flags().String("key", "", "Path to validator key file (required)")
+	unjailCmd.Flags().String("chain-id", "apex-mainnet-1", "Chain ID")
+	unjailCmd.Flags().Uint64("nonce", 0, "Account nonce of the validator")
+	unjailCmd.MarkFlagRequired("key")
 
 	cmd.AddCommand(createCmd, unjailCmd)
 	return cmd
@@ -190,6 +202,11 @@ func runValidatorCreate(cmd *cobra.Command, args []string) {
 	details, _ := cmd.Flags().GetString("details")
 	website, _ := cmd.Flags().GetString("website")
 
+	description := types.ValidatorDescription{Moniker: moniker, Website: website, Details: details}
+	if err := description.Validate(); err != nil {
+		logger.Fatal("Invalid validator description", zap.Error(err))
+	}
+
 	fmt.Printf("Creating validator:\n")
 	fmt.Printf("  Moniker: %s\n", moniker)
 	fmt.Printf("  Commission: %.2f%%\n", float64(commission)/100)
@@ -202,8 +219,43 @@ func runValidatorCreate(cmd *cobra.Command, args []string) {
 }
 
 func runValidatorUnjail(cmd *cobra.Command, args []string) {
-	fmt.Printf("✓ Unjail transaction submitted\n")
-	fmt.Printf("Transaction hash: 0x%s\n", generateRandomHex(64))
+	keyFile, _ := cmd.Flags().GetString("key")
+	chainID, _ := cmd.Flags().GetString("chain-id")
+	nonce, _ := cmd.Flags().GetUint64("nonce")
+
+	privKey, err := loadKey(keyFile)
+	if err != nil {
+		logger.Fatal("Failed to load validator key", zap.Error(err))
+	}
+	address := crypto.PublicKeyToAddress(&privKey.PublicKey)
+
+	tx := core.NewTransaction(core.TxTypeUnjail, address, types.Address{}, types.ToWei(0), nil, nonce)
+	tx.ChainID = chainID
+	if err := tx.SignWithKey(privKey); err != nil {
+		logger.Fatal("Failed to sign unjail transaction", zap.Error(err))
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		logger.Fatal("Failed to encode unjail transaction", zap.Error(err))
+	}
+
+	fmt.Printf("✓ Unjail transaction signed\n")
+	fmt.Printf("Validator: %s\n", address.Hex())
+	fmt.Printf("Transaction hash: %s\n", tx.Hash.Hex())
+	fmt.Printf("Raw transaction: 0x%s\n", hex.EncodeToString(raw))
+}
+
+// loadKey reads the private key from a key file
+func loadKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keyData map[string]string
+	if err := json.Unmarshal(data, &keyData); err != nil {
+		return nil, err
+	}
+	return crypto.HexToPrivateKey(strings.TrimPrefix(keyData["private_key"], "0x"))
 }
 
 func runStake(cmd *cobra.Command, args []string) {
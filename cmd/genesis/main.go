@@ -1,61 +1,18 @@
 package main
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"time"
 
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/genesis"
 	"github.com/spf13/cobra"
 )
 
-// GenesisConfig represents genesis configuration
-type GenesisConfig struct {
-	ChainID       string              `json:"chain_id"`
-	GenesisTime   time.Time           `json:"genesis_time"`
-	TotalSupply   string              `json:"total_supply"`
-	Validators    []GenesisValidator  `json:"initial_validators"`
-	Accounts      []GenesisAccount    `json:"initial_accounts"`
-	ConsensusParams ConsensusParams   `json:"consensus_params"`
-	RewardParams  RewardParams        `json:"reward_params"`
-}
-
-// GenesisValidator represents a genesis validator
-type GenesisValidator struct {
-	Address    string `json:"address"`
-	PublicKey  string `json:"public_key"`
-	VotingPower string `json:"voting_power"`
-	Commission uint64 `json:"commission"`
-	Moniker    string `json:"moniker"`
-	Website    string `json:"website"`
-	Details    string `json:"details"`
-}
-
-// GenesisAccount represents a genesis account
-type GenesisAccount struct {
-	Address     string `json:"address"`
-	Balance     string `json:"balance"`
-	Description string `json:"description"`
-}
-
-// ConsensusParams represents consensus parameters
-type ConsensusParams struct {
-	BlockTime              int     `json:"block_time"`
-	MaxValidators          int     `json:"max_validators"`
-	MinStake               string  `json:"min_stake"`
-	UnbondingPeriod        uint64  `json:"unbonding_period"`
-	SlashFractionDoubleSign float64 `json:"slash_fraction_double_sign"`
-	SlashFractionDowntime  float64 `json:"slash_fraction_downtime"`
-}
-
-// RewardParams represents reward parameters
-type RewardParams struct {
-	InitialReward  string `json:"initial_reward"`
-	HalvingPeriod  uint64 `json:"halving_period"`
-	MinimumReward  string `json:"minimum_reward"`
-}
-
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "genesis",
@@ -82,6 +39,7 @@ func createCmd() *cobra.Command {
 
 	cmd.Flags().String("chain-id", "apex-mainnet-1", "Chain ID")
 	cmd.Flags().String("output", "genesis.json", "Output file path")
+	cmd.Flags().String("keys-output", "validator_keys.json", "Output file for the generated validator private keys")
 
 	return cmd
 }
@@ -106,15 +64,15 @@ func exportCmd() *cobra.Command {
 func runCreate(cmd *cobra.Command, args []string) {
 	chainID, _ := cmd.Flags().GetString("chain-id")
 	output, _ := cmd.Flags().GetString("output")
+	keysOutput, _ := cmd.Flags().GetString("keys-output")
+	weighted := true
 
-	genesis := GenesisConfig{
+	config := genesis.Genesis{
 		ChainID:     chainID,
-		GenesisTime: time.Now().UTC(),
+		GenesisTime: time.Now().UTC().Truncate(time.Second),
 		TotalSupply: "500000000000000000000000000", // 500M APX
-		Validators: []GenesisValidator{
+		Validators: []genesis.Validator{
 			{
-				Address:     "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb0",
-				PublicKey:   "0x04abcd...",
 				VotingPower: "1000000000000000000000000", // 1M APX
 				Commission:  1000, // 10%
 				Moniker:     "Genesis Validator 1",
@@ -122,8 +80,6 @@ func runCreate(cmd *cobra.Command, args []string) {
 				Details:     "Primary genesis validator",
 			},
 			{
-				Address:     "0x8626f6940E2eb28930eFb4CeF49B2d1F2C9C1199",
-				PublicKey:   "0x04ef01...",
 				VotingPower: "800000000000000000000000", // 800K APX
 				Commission:  800, // 8%
 				Moniker:     "Genesis Validator 2",
@@ -131,7 +87,7 @@ func runCreate(cmd *cobra.Command, args []string) {
 				Details:     "Secondary genesis validator",
 			},
 		},
-		Accounts: []GenesisAccount{
+		Accounts: []genesis.Account{
 			{
 				Address:     "0x0000000000000000000000000000000000000001",
 				Balance:     "100000000000000000000000000", // 100M APX
@@ -153,27 +109,64 @@ func runCreate(cmd *cobra.Command, args []string) {
 				Description: "Marketing Fund",
 			},
 		},
-		ConsensusParams: ConsensusParams{
+		ConsensusParams: genesis.ConsensusParams{
 			BlockTime:              3,
 			MaxValidators:          21,
+			EpochLength:            1200,
+			ValidatorSetDelay:      2,
+			WeightedSchedule:       &weighted,
 			MinStake:               "100000000000000000000000", // 100K APX
+			MinDelegation:          "10000000000000000000",     // 10 APX
 			UnbondingPeriod:        201600,
+			SignedSlotsWindow:      1000,
+			MinSignedPerWindow:     0.5,
+			DowntimeJailDuration:   28800,
+			MaxCommissionChangeRate: 100,
 			SlashFractionDoubleSign: 0.05,
 			SlashFractionDowntime:  0.01,
 		},
-		RewardParams: RewardParams{
+		RewardParams: genesis.RewardParams{
 			InitialReward: "2000000000000000000", // 2 APX
 			HalvingPeriod: 10512000,
 			MinimumReward: "100000000000000000", // 0.1 APX
 		},
 	}
 
-	data, err := json.MarshalIndent(genesis, "", "  ")
+	// Generate a key for each genesis validator
+	keys := make(map[string]string, len(config.Validators))
+	for i := range config.Validators {
+		privKey, pubKey, err := crypto.GenerateKeyPair()
+		if err != nil {
+			fmt.Printf("Error generating validator key: %v\n", err)
+			os.Exit(1)
+		}
+		address := "0x" + crypto.PublicKeyToAddress(pubKey).Hex()
+		config.Validators[i].Address = address
+		config.Validators[i].PublicKey = "0x" + hex.EncodeToString(crypto.PublicKeyToBytes(pubKey))
+		keys[address] = crypto.PrivateKeyToHex(privKey)
+	}
+
+	if err := config.Validate(); err != nil {
+		fmt.Printf("Error validating genesis: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		fmt.Printf("Error marshaling genesis: %v\n", err)
 		os.Exit(1)
 	}
 
+	keyData, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling validator keys: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(keysOutput, keyData, 0600); err != nil {
+		fmt.Printf("Error writing validator keys: %v\n", err)
+		os.Exit(1)
+	}
+
 	if err := ioutil.WriteFile(output, data, 0644); err != nil {
 		fmt.Printf("Error writing genesis file: %v\n", err)
 		os.Exit(1)
@@ -183,45 +176,26 @@ func runCreate(cmd *cobra.Command, args []string) {
 	fmt.Printf("Chain ID: %s\n", chainID)
 	fmt.Printf("Output file: %s\n", output)
 	fmt.Printf("Total Supply: 500,000,000 APX\n")
-	fmt.Printf("Validators: %d\n", len(genesis.Validators))
-	fmt.Printf("Genesis Accounts: %d\n", len(genesis.Accounts))
+	fmt.Printf("Validator keys: %s\n", keysOutput)
+	fmt.Printf("Validators: %d\n", len(config.Validators))
+	fmt.Printf("Genesis Accounts: %d\n", len(config.Accounts))
 }
 
 func runValidate(cmd *cobra.Command, args []string) {
 	file := args[0]
 
-	data, err := ioutil.ReadFile(file)
-	if err != nil {
-		fmt.Printf("Error reading genesis file: %v\n", err)
-		os.Exit(1)
-	}
-
-	var genesis GenesisConfig
-	if err := json.Unmarshal(data, &genesis); err != nil {
-		fmt.Printf("Error parsing genesis file: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Validation checks
 	fmt.Println("Validating genesis configuration...")
-	
-	if genesis.ChainID == "" {
-		fmt.Println("✗ Chain ID is required")
-		os.Exit(1)
-	}
-	fmt.Println("✓ Chain ID is valid")
 
-	if len(genesis.Validators) == 0 {
-		fmt.Println("✗ At least one validator is required")
+	config, err := genesis.Load(file)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("✓ %d validators configured\n", len(genesis.Validators))
 
-	if len(genesis.Accounts) == 0 {
-		fmt.Println("✗ At least one account is required")
-		os.Exit(1)
-	}
-	fmt.Printf("✓ %d accounts configured\n", len(genesis.Accounts))
+	fmt.Printf("✓ Chain ID: %s\n", config.ChainID)
+	fmt.Printf("✓ Genesis time: %s\n", config.GenesisTime.Format(time.RFC3339))
+	fmt.Printf("✓ %d validators configured\n", len(config.Validators))
+	fmt.Printf("✓ %d accounts configured\n", len(config.Accounts))
 
 	fmt.Println("\n✓ Genesis configuration is valid")
 }
@@ -1,6 +1,7 @@
 package jsonrpc
 
 import (
+	"encoding/hex"
 	"errors"
 	"math/big"
 
@@ -33,6 +34,10 @@ func (h *Handler) Handle(req *RPCRequest) (interface{}, error) {
 		return h.handleGetBlockByHash(req)
 	case "apex_getTransaction":
 		return h.handleGetTransaction(req)
+	case "apex_getTransactionProof":
+		return h.handleGetTransactionProof(req)
+	case "apex_getTransactionReceipt":
+		return h.handleGetTransactionReceipt(req)
 	case "apex_sendTransaction":
 		return h.handleSendTransaction(req)
 	case "apex_getValidators":
@@ -43,6 +48,16 @@ func (h *Handler) Handle(req *RPCRequest) (interface{}, error) {
 		return h.handleUnstake(req)
 	case "apex_getStakingInfo":
 		return h.handleGetStakingInfo(req)
+	case "apex_getParams":
+		return h.handleGetParams(req)
+	case "apex_estimateFees":
+		return h.handleEstimateFees(req)
+	case "apex_getUnbondingDelegations":
+		return h.handleGetUnbondingDelegations(req)
+	case "apex_getRedelegations":
+		return h.handleGetRedelegations(req)
+	case "apex_getSlashingEvents":
+		return h.handleGetSlashingEvents(req)
 	default:
 		return nil, errors.New("method not found")
 	}
@@ -52,7 +67,8 @@ func (h *Handler) Handle(req *RPCRequest) (interface{}, error) {
 func (h *Handler) handleBlockNumber(req *RPCRequest) (interface{}, error) {
 	height := h.blockchain.GetHeight()
 	return map[string]interface{}{
-		"blockNumber": height,
+		"blockNumber":     height,
+		"finalizedNumber": h.blockchain.GetFinalizedHeight(),
 	}, nil
 }
 
@@ -68,7 +84,7 @@ func (h *Handler) handleGetBalance(req *RPCRequest) (interface{}, error) {
 	}
 	
 	addr := types.HexToAddress(addrStr)
-	account, err := h.blockchain.GetStateDB().GetAccount(addr)
+	account, err := h.blockchain.GetAccount(addr)
 	if err != nil {
 		return nil, err
 	}
@@ -88,12 +104,12 @@ func (h *Handler) handleGetBlockByNumber(req *RPCRequest) (interface{}, error) {
 		return nil, errors.New("missing block number parameter")
 	}
 	
-	blockNum, ok := req.Params[0].(float64)
-	if !ok {
-		return nil, errors.New("invalid block number parameter")
+	blockNum, err := h.parseBlockNumber(req.Params[0])
+	if err != nil {
+		return nil, err
 	}
 	
-	block, err := h.blockchain.GetBlockByNumber(uint64(blockNum))
+	block, err := h.blockchain.GetBlockByNumber(blockNum)
 	if err != nil {
 		return nil, err
 	}
@@ -101,6 +117,25 @@ func (h *Handler) handleGetBlockByNumber(req *RPCRequest) (interface{}, error) {
 	return h.formatBlock(block), nil
 }
 
+// parseBlockNumber resolves a block number parameter: a number, or one of
+// the tags "earliest", "latest" and "finalized"
+func (h *Handler) parseBlockNumber(param interface{}) (uint64, error) {
+	switch v := param.(type) {
+	case float64:
+		return uint64(v), nil
+	case string:
+		switch v {
+		case "earliest":
+			return 0, nil
+		case "latest":
+			return h.blockchain.GetHeight(), nil
+		case "finalized":
+			return h.blockchain.GetFinalizedHeight(), nil
+		}
+	}
+	return 0, errors.New("invalid block number parameter")
+}
+
 // handleGetBlockByHash returns block by hash
 func (h *Handler) handleGetBlockByHash(req *RPCRequest) (interface{}, error) {
 	if len(req.Params) < 1 {
@@ -123,7 +158,7 @@ func (h *Handler) handleGetBlockByHash(req *RPCRequest) (interface{}, error) {
 
 // handleGetValidators returns active validators
 func (h *Handler) handleGetValidators(req *RPCRequest) (interface{}, error) {
-	validators, err := h.blockchain.GetStateDB().GetAllValidators()
+	validators, err := h.blockchain.GetValidators()
 	if err != nil {
 		return nil, err
 	}
@@ -132,13 +167,246 @@ func (h *Handler) handleGetValidators(req *RPCRequest) (interface{}, error) {
 	for i, val := range validators {
 		result[i] = map[string]interface{}{
 			"address":      val.Address.Hex(),
+			"moniker":      val.Description.Moniker,
+			"website":      val.Description.Website,
+			"details":      val.Description.Details,
 			"voting_power": types.FromWei(val.VotingPower),
 			"commission":   float64(val.Commission) / 100,
 			"status":       val.Status,
 			"jailed":       val.Jailed,
+			"jailed_until": val.JailedUntil,
+			"tombstoned":   val.Tombstoned,
+		}
+	}
+	
+	return result, nil
+}
+
+// handleGetParams returns the consensus, staking and reward parameters
+func (h *Handler) handleGetParams(req *RPCRequest) (interface{}, error) {
+	params, err := h.blockchain.GetParams()
+	if err != nil {
+		return nil, err
+	}
+	
+	return map[string]interface{}{
+		"block_time":                   params.BlockTime,
+		"max_validators":               params.MaxValidators,
+		"epoch_length":                 params.EpochLength,
+		"validator_set_delay":          params.ValidatorSetDelay,
+		"weighted_schedule":            params.WeightedSchedule,
+		"min_stake":                    params.MinStake.String(),
+		"min_delegation":               params.MinDelegation.String(),
+		"unbonding_period":             params.UnbondingPeriod,
+		"signed_slots_window":          params.SignedSlotsWindow,
+		"min_signed_per_window":        float64(params.MinSignedPerWindow) / 10000,
+		"downtime_jail_duration":       params.DowntimeJailDuration,
+		"max_commission_change_rate":   float64(params.MaxCommissionChangeRate) / 10000,
+		"slash_fraction_double_sign":   float64(params.SlashFractionDoubleSign) / 10000,
+		"slash_fraction_downtime":      float64(params.SlashFractionDowntime) / 10000,
+		"slash_fraction_invalid_block": float64(params.SlashFractionInvalidBlock) / 10000,
+		"initial_reward":               params.InitialReward.String(),
+		"halving_period":               params.HalvingPeriod,
+		"minimum_reward":               params.MinimumReward.String(),
+	}, nil
+}
+
+// handleGetUnbondingDelegations returns the pending unbonding entries of a
+// delegator
+func (h *Handler) handleGetUnbondingDelegations(req *RPCRequest) (interface{}, error) {
+	if len(req.Params) < 1 {
+		return nil, errors.New("missing address parameter")
+	}
+	
+	addrStr, ok := req.Params[0].(string)
+	if !ok {
+		return nil, errors.New("invalid address parameter")
+	}
+	
+	unbondings, err := h.blockchain.GetUnbondingDelegations(types.HexToAddress(addrStr))
+	if err != nil {
+		return nil, err
+	}
+	
+	result := make([]map[string]interface{}, len(unbondings))
+	for i, ubd := range unbondings {
+		result[i] = map[string]interface{}{
+			"delegator":        ubd.Delegator.Hex(),
+			"validator":        ubd.Validator.Hex(),
+			"amount":           types.FromWei(ubd.Amount),
+			"creation_block":   ubd.CreationHeight,
+			"completion_block": ubd.CompletionBlock,
+		}
+	}
+	
+	return result, nil
+}
+
+// handleGetRedelegations returns the maturing redelegation entries of a
+// delegator
+func (h *Handler) handleGetRedelegations(req *RPCRequest) (interface{}, error) {
+	if len(req.Params) < 1 {
+		return nil, errors.New("missing address parameter")
+	}
+	
+	addrStr, ok := req.Params[0].(string)
+	if !ok {
+		return nil, errors.New("invalid address parameter")
+	}
+	
+	redelegations, err := h.blockchain.GetRedelegations(types.HexToAddress(addrStr))
+	if err != nil {
+		return nil, err
+	}
+	
+	result := make([]map[string]interface{}, len(redelegations))
+	for i, red := range redelegations {
+		result[i] = map[string]interface{}{
+			"delegator":        red.Delegator.Hex(),
+			"validator_src":    red.ValidatorSrc.Hex(),
+			"validator_dst":    red.ValidatorDst.Hex(),
+			"amount":           types.FromWei(red.Amount),
+			"creation_block":   red.CreationHeight,
+			"completion_block": red.CompletionBlock,
+		}
+	}
+	
+	return result, nil
+}
+
+// handleGetSlashingEvents returns the slashing events of a validator
+func (h *Handler) handleGetSlashingEvents(req *RPCRequest) (interface{}, error) {
+	if len(req.Params) < 1 {
+		return nil, errors.New("missing address parameter")
+	}
+	
+	addrStr, ok := req.Params[0].(string)
+	if !ok {
+		return nil, errors.New("invalid address parameter")
+	}
+	
+	events, err := h.blockchain.GetSlashingEvents(types.HexToAddress(addrStr))
+	if err != nil {
+		return nil, err
+	}
+	
+	result := make([]map[string]interface{}, len(events))
+	for i, event := range events {
+		result[i] = map[string]interface{}{
+			"validator":        event.Validator.Hex(),
+			"reason":           event.Reason,
+			"fraction":         event.Fraction,
+			"amount":           types.FromWei(event.Amount),
+			"infraction_block": event.InfractionHeight,
+			"block":            event.Height,
+		}
+	}
+	
+	return result, nil
+}
+
+// handleEstimateFees suggests fee caps for a transaction in the next block.
+// The suggested max fee leaves room for the base fee to double.
+func (h *Handler) handleEstimateFees(req *RPCRequest) (interface{}, error) {
+	baseFee := h.blockchain.NextBaseFee()
+	tip := h.blockchain.SuggestPriorityFee()
+	maxFee := new(big.Int).Mul(baseFee, big.NewInt(2))
+	maxFee.Add(maxFee, tip)
+	
+	return map[string]interface{}{
+		"baseFee":              baseFee.String(),
+		"maxPriorityFeePerGas": tip.String(),
+		"maxFeePerGas":         maxFee.String(),
+	}, nil
+}
+
+// handleGetTransactionProof returns the Merkle inclusion proof of a transaction
+func (h *Handler) handleGetTransactionProof(req *RPCRequest) (interface{}, error) {
+	if len(req.Params) < 2 {
+		return nil, errors.New("missing block number or transaction index parameter")
+	}
+	
+	blockNum, err := h.parseBlockNumber(req.Params[0])
+	if err != nil {
+		return nil, err
+	}
+	
+	txIndex, ok := req.Params[1].(float64)
+	if !ok {
+		return nil, errors.New("invalid transaction index parameter")
+	}
+	
+	block, err := h.blockchain.GetBlockByNumber(blockNum)
+	if err != nil {
+		return nil, err
+	}
+	
+	proof, err := block.GetTransactionProof(int(txIndex))
+	if err != nil {
+		return nil, err
+	}
+	
+	siblings := make([]string, len(proof.Siblings))
+	for i, sibling := range proof.Siblings {
+		siblings[i] = sibling.Hex()
+	}
+	
+	return map[string]interface{}{
+		"blockNumber":     block.Header.Number,
+		"blockHash":       block.Hash.Hex(),
+		"transactionRoot": block.Header.TransactionRoot.Hex(),
+		"transactionHash": block.Transactions[proof.Index].Hash.Hex(),
+		"index":           proof.Index,
+		"total":           proof.Total,
+		"siblings":        siblings,
+	}, nil
+}
+
+// handleGetTransactionReceipt returns the receipt of a transaction
+func (h *Handler) handleGetTransactionReceipt(req *RPCRequest) (interface{}, error) {
+	if len(req.Params) < 1 {
+		return nil, errors.New("missing transaction hash parameter")
+	}
+	
+	hashStr, ok := req.Params[0].(string)
+	if !ok {
+		return nil, errors.New("invalid transaction hash parameter")
+	}
+	
+	receipt, err := h.blockchain.GetReceipt(types.HexToHash(hashStr))
+	if err != nil {
+		return nil, err
+	}
+	
+	logs := make([]map[string]interface{}, len(receipt.Logs))
+	for i, log := range receipt.Logs {
+		topics := make([]string, len(log.Topics))
+		for j, topic := range log.Topics {
+			topics[j] = topic.Hex()
+		}
+		logs[i] = map[string]interface{}{
+			"address": log.Address.Hex(),
+			"topics":  topics,
+			"data":    hex.EncodeToString(log.Data),
 		}
 	}
 	
+	result := map[string]interface{}{
+		"transactionHash":   receipt.TxHash.Hex(),
+		"blockHash":         receipt.BlockHash.Hex(),
+		"blockNumber":       receipt.BlockNumber,
+		"transactionIndex":  receipt.TxIndex,
+		"from":              receipt.From.Hex(),
+		"to":                receipt.To.Hex(),
+		"gasUsed":           receipt.GasUsed,
+		"effectiveGasPrice": receipt.EffectiveGasPrice.String(),
+		"status":            receipt.Status,
+		"logs":              logs,
+	}
+	if receipt.Error != "" {
+		result["error"] = receipt.Error
+	}
+	
 	return result, nil
 }
 
@@ -157,10 +425,14 @@ func (h *Handler) formatBlock(block *core.Block) map[string]interface{} {
 		"validator":        block.Header.Validator.Hex(),
 		"transactionRoot":  block.Header.TransactionRoot.Hex(),
 		"stateRoot":        block.Header.StateRoot.Hex(),
+		"receiptsRoot":     block.Header.ReceiptsRoot.Hex(),
+		"evidenceRoot":     block.Header.EvidenceRoot.Hex(),
 		"gasUsed":          block.Header.GasUsed,
 		"gasLimit":         block.Header.GasLimit,
+		"baseFee":          block.Header.BaseFee.String(),
 		"transactions":     txs,
 		"transactionCount": len(txs),
+		"evidenceCount":    len(block.Evidence),
 	}
 }
 
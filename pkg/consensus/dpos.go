@@ -1,74 +1,142 @@
 package consensus
 
 import (
+	"bytes"
 	"errors"
 	"math/big"
 	"sort"
 	"sync"
 	"time"
 
-	
 	"github.com/apex/pkg/types"
 )
 
-// DPoS implements Delegated Proof of Stake consensus
+// StakingState is the persisted validator and delegation state DPoS reads
+// and writes. It is implemented by the state database, so staking
+// transactions and consensus see the same validators.
+type StakingState interface {
+	GetValidator(addr types.Address) (*types.Validator, error)
+	SetValidator(validator *types.Validator) error
+	GetAllValidators() ([]*types.Validator, error)
+	GetDelegation(delegator, validator types.Address) (*types.Delegation, error)
+	SetDelegation(delegation *types.Delegation) error
+	DeleteDelegation(delegator, validator types.Address) error
+	GetDelegationsByValidator(validator types.Address) ([]*types.Delegation, error)
+	GetValidatorSet(epoch uint64) (*types.ValidatorSet, error)
+	SetValidatorSet(set *types.ValidatorSet) error
+	GetSigningInfo(addr types.Address) (*types.SigningInfo, error)
+	SetSigningInfo(info *types.SigningInfo) error
+	AddSlashingEvent(event *types.SlashingEvent) error
+	GetSlashingEvents(validator types.Address) ([]*types.SlashingEvent, error)
+	GetAllSlashingEvents() ([]*types.SlashingEvent, error)
+}
+
+// DPoS implements Delegated Proof of Stake consensus. Validators,
+// delegations and the validator set of every epoch live in state. The set
+// selected at the end of epoch N produces the blocks of epoch
+// N+ValidatorSetDelay, so every node knows the producers of an epoch well
+// before it starts.
 type DPoS struct {
-	validators       map[types.Address]*types.Validator
-	delegations      map[types.Address]map[types.Address]*types.Delegation // delegator -> validator -> delegation
-	activeValidators []*types.Validator
-	currentEpoch     uint64
-	mu               sync.RWMutex
+	state        StakingState
+	currentEpoch uint64
+	params       *types.Params
+	schedule     *epochSchedule // producer schedule of the last epoch queried
+	mu           sync.RWMutex
+}
+
+// epochSchedule caches the producer schedule derived from a validator set
+type epochSchedule struct {
+	epoch uint64
+	seed  types.Hash
+	slots []types.Address
 }
 
-// NewDPoS creates a new DPoS consensus engine
-func NewDPoS() *DPoS {
+// NewDPoS creates a new DPoS consensus engine backed by state
+func NewDPoS(state StakingState) *DPoS {
 	return &DPoS{
-		validators:       make(map[types.Address]*types.Validator),
-		delegations:      make(map[types.Address]map[types.Address]*types.Delegation),
-		activeValidators: make([]*types.Validator, 0),
-		currentEpoch:     0,
+		state:        state,
+		currentEpoch: 0,
+		params:       types.DefaultParams(),
 	}
 }
 
-// RegisterValidator registers a new validator
-func (d *DPoS) RegisterValidator(validator *types.Validator) error {
+// SetParams sets the chain parameters used by consensus
+func (d *DPoS) SetParams(params *types.Params) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	
-	if _, exists := d.validators[validator.Address]; exists {
+	d.params = params
+}
+
+// Params returns the chain parameters used by consensus
+func (d *DPoS) Params() *types.Params {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	return d.params
+}
+
+// RegisterValidator registers a new validator
+func (d *DPoS) RegisterValidator(validator *types.Validator) error {
+	if _, err := d.state.GetValidator(validator.Address); err == nil {
 		return errors.New("validator already registered")
 	}
 	
 	// Check minimum stake requirement
-	minStake := types.ToWei(float64(types.MinStakeAmount))
-	if validator.SelfStake.Cmp(minStake) < 0 {
+	if validator.SelfStake.Cmp(d.Params().MinStake) < 0 {
 		return errors.New("insufficient self-stake")
 	}
 	
-	d.validators[validator.Address] = validator
-	return nil
+	return d.state.SetValidator(validator)
+}
+
+// UpdateValidator writes back a validator changed by the caller
+func (d *DPoS) UpdateValidator(validator *types.Validator) error {
+	return d.state.SetValidator(validator)
+}
+
+// UpdateDelegation writes back a delegation changed by the caller
+func (d *DPoS) UpdateDelegation(delegation *types.Delegation) error {
+	return d.state.SetDelegation(delegation)
+}
+
+// GetSigningInfo returns the signing window of a validator, empty if none
+// was recorded yet
+func (d *DPoS) GetSigningInfo(addr types.Address) *types.SigningInfo {
+	info, err := d.state.GetSigningInfo(addr)
+	if err != nil {
+		return types.NewSigningInfo(addr)
+	}
+	return info
+}
+
+// UpdateSigningInfo writes back a signing window changed by the caller
+func (d *DPoS) UpdateSigningInfo(info *types.SigningInfo) error {
+	return d.state.SetSigningInfo(info)
 }
 
-// Delegate allows a user to delegate stake to a validator
-func (d *DPoS) Delegate(delegator, validator types.Address, amount *big.Int) error {
+// LoadState restores the epoch after a restart
+func (d *DPoS) LoadState(epoch uint64) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	
+	d.currentEpoch = epoch
+}
+
+// Delegate allows a user to delegate stake to a validator in the block with
+// timestamp blockTime
+func (d *DPoS) Delegate(delegator, validator types.Address, amount *big.Int, blockTime time.Time) error {
 	// Check validator exists
-	val, exists := d.validators[validator]
-	if !exists {
+	val, err := d.state.GetValidator(validator)
+	if err != nil {
 		return errors.New("validator not found")
 	}
 	
 	// Create or update delegation
-	if d.delegations[delegator] == nil {
-		d.delegations[delegator] = make(map[types.Address]*types.Delegation)
-	}
-	
-	delegation, exists := d.delegations[delegator][validator]
-	if !exists {
+	delegation, err := d.state.GetDelegation(delegator, validator)
+	if err != nil {
 		delegation = types.NewDelegation(delegator, validator, amount)
-		d.delegations[delegator][validator] = delegation
+		delegation.CreatedAt = blockTime
 	} else {
 		delegation.Amount.Add(delegation.Amount, amount)
 	}
@@ -76,17 +144,17 @@ func (d *DPoS) Delegate(delegator, validator types.Address, amount *big.Int) err
 	// Update validator voting power
 	val.AddVotingPower(amount)
 	
-	return nil
+	if err := d.state.SetDelegation(delegation); err != nil {
+		return err
+	}
+	return d.state.SetValidator(val)
 }
 
 // Undelegate initiates undelegation
 func (d *DPoS) Undelegate(delegator, validator types.Address, amount *big.Int) error {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	
 	// Check delegation exists
-	delegation, exists := d.delegations[delegator][validator]
-	if !exists {
+	delegation, err := d.state.GetDelegation(delegator, validator)
+	if err != nil {
 		return errors.New("delegation not found")
 	}
 	
@@ -94,154 +162,296 @@ func (d *DPoS) Undelegate(delegator, validator types.Address, amount *big.Int) e
 		return errors.New("insufficient delegated amount")
 	}
 	
+	val, err := d.state.GetValidator(validator)
+	if err != nil {
+		return err
+	}
+	
 	// Update delegation
 	delegation.Amount.Sub(delegation.Amount, amount)
 	
 	// Update validator voting power
-	val := d.validators[validator]
 	val.SubVotingPower(amount)
 	
 	// Remove delegation if amount is zero
 	if delegation.Amount.Sign() == 0 {
-		delete(d.delegations[delegator], validator)
+		err = d.state.DeleteDelegation(delegator, validator)
+	} else {
+		err = d.state.SetDelegation(delegation)
 	}
-	
-	return nil
+	if err != nil {
+		return err
+	}
+	return d.state.SetValidator(val)
 }
 
-// SelectValidators selects active validators for next epoch
-func (d *DPoS) SelectValidators() []*types.Validator {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	
-	// Get all validators
-	validators := make([]*types.Validator, 0, len(d.validators))
-	for _, val := range d.validators {
-		if val.CanProduceBlocks() {
+// SelectActiveSet picks the validators of an epoch from candidates: those
+// able to produce blocks, by voting power (descending) and then by address so
+// every node derives the same order, at most params.MaxValidators of them
+func SelectActiveSet(candidates []*types.Validator, params *types.Params) []*types.Validator {
+	validators := make([]*types.Validator, 0, len(candidates))
+	for _, val := range candidates {
+		if val.CanProduceBlocks(params.MinStake) {
 			validators = append(validators, val)
 		}
 	}
 	
-	// Sort by voting power (descending)
 	sort.Slice(validators, func(i, j int) bool {
-		return validators[i].VotingPower.Cmp(validators[j].VotingPower) > 0
+		if c := validators[i].VotingPower.Cmp(validators[j].VotingPower); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(validators[i].Address[:], validators[j].Address[:]) < 0
 	})
 	
 	// Select top MaxValidators
-	maxValidators := types.MaxValidators
-	if len(validators) < maxValidators {
-		maxValidators = len(validators)
+	if len(validators) > params.MaxValidators {
+		validators = validators[:params.MaxValidators]
 	}
-	
-	d.activeValidators = validators[:maxValidators]
-	return d.activeValidators
+	return validators
 }
 
-// GetBlockProducer returns the validator that should produce the next block
-func (d *DPoS) GetBlockProducer(blockNumber uint64) (*types.Validator, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+// GenesisValidatorSets returns the sets of the first ValidatorSetDelay
+// epochs, all made of the genesis validators, which produce blocks until the
+// first selected set takes over. With no blocks to draw from, their seed is
+// zero.
+func GenesisValidatorSets(validators []*types.Validator, params *types.Params) []*types.ValidatorSet {
+	active := SelectActiveSet(validators, params)
+	
+	sets := make([]*types.ValidatorSet, params.ValidatorSetDelay)
+	for epoch := range sets {
+		sets[epoch] = newValidatorSet(uint64(epoch), active, types.Hash{}, params.WeightedSchedule)
+	}
+	return sets
+}
+
+// newValidatorSet creates the set of an epoch from selected validators,
+// recording their voting power and weighting their slots by it if weighted
+func newValidatorSet(epoch uint64, validators []*types.Validator, seed types.Hash, weighted bool) *types.ValidatorSet {
+	set := &types.ValidatorSet{
+		Epoch:      epoch,
+		Validators: make([]types.Address, len(validators)),
+		Powers:     make([]*big.Int, len(validators)),
+		Seed:       seed,
+	}
+	for i, val := range validators {
+		set.Validators[i] = val.Address
+		set.Powers[i] = new(big.Int).Set(val.VotingPower)
+	}
+	if weighted {
+		set.Weights = make([]*big.Int, len(validators))
+		for i, val := range validators {
+			set.Weights[i] = new(big.Int).Set(val.VotingPower)
+		}
+	}
+	return set
+}
+
+// SelectValidators selects the validators that would be active given the
+// current state
+func (d *DPoS) SelectValidators() ([]*types.Validator, error) {
+	all, err := d.state.GetAllValidators()
+	if err != nil {
+		return nil, err
+	}
+	return SelectActiveSet(all, d.Params()), nil
+}
+
+// EpochOf returns the epoch of a block
+func (d *DPoS) EpochOf(blockNumber uint64) uint64 {
+	return blockNumber / d.Params().EpochLength
+}
+
+// IsEpochEnd reports whether a block is the last block of its epoch
+func (d *DPoS) IsEpochEnd(blockNumber uint64) bool {
+	return (blockNumber+1)%d.Params().EpochLength == 0
+}
+
+// ScheduleValidatorSet selects the validator set of a future epoch at
+// blockNumber, the last block of epoch N. The set is stored for epoch
+// N+ValidatorSetDelay with seed, which shuffles its producer schedule; if no
+// validator qualifies, the previous set stays.
+func (d *DPoS) ScheduleValidatorSet(blockNumber uint64, seed types.Hash) error {
+	params := d.Params()
+	epoch := d.EpochOf(blockNumber) + params.ValidatorSetDelay
+	
+	validators, err := d.SelectValidators()
+	if err != nil {
+		return err
+	}
+	if len(validators) == 0 {
+		previous, err := d.state.GetValidatorSet(epoch - 1)
+		if err != nil {
+			return err
+		}
+		carried := *previous
+		carried.Epoch = epoch
+		carried.Seed = seed
+		return d.state.SetValidatorSet(&carried)
+	}
+	return d.state.SetValidatorSet(newValidatorSet(epoch, validators, seed, params.WeightedSchedule))
+}
+
+// SelectedBy reports whether the validator set producing the block at
+// blockNumber was selected at or before the block at height
+func (d *DPoS) SelectedBy(blockNumber, height uint64) bool {
+	params := d.Params()
+	epoch := d.EpochOf(blockNumber)
+	if epoch < params.ValidatorSetDelay {
+		// Selected at genesis
+		return true
+	}
+	return (epoch-params.ValidatorSetDelay+1)*params.EpochLength-1 <= height
+}
+
+// GetValidatorSet returns the validator set of an epoch
+func (d *DPoS) GetValidatorSet(epoch uint64) (*types.ValidatorSet, error) {
+	set, err := d.state.GetValidatorSet(epoch)
+	if err != nil {
+		return nil, errors.New("no validator set for epoch")
+	}
+	return set, nil
+}
+
+// GetSlotProducer returns the validator scheduled for a slot. The schedule
+// is that of the epoch of blockNumber, the block being produced in the slot.
+func (d *DPoS) GetSlotProducer(blockNumber, slot uint64) (*types.Validator, error) {
+	epoch := d.EpochOf(blockNumber)
+	set, err := d.GetValidatorSet(epoch)
+	if err != nil {
+		return nil, err
+	}
 	
-	if len(d.activeValidators) == 0 {
+	schedule := d.epochSchedule(set)
+	if len(schedule) == 0 {
 		return nil, errors.New("no active validators")
 	}
 	
-	// Round-robin selection based on block number
-	index := blockNumber % uint64(len(d.activeValidators))
-	return d.activeValidators[index], nil
+	return d.state.GetValidator(schedule[slot%uint64(len(schedule))])
+}
+
+// epochSchedule returns the producer schedule of a validator set, reusing
+// the cached one if it was derived from the same set
+func (d *DPoS) epochSchedule(set *types.ValidatorSet) []types.Address {
+	d.mu.RLock()
+	cached := d.schedule
+	d.mu.RUnlock()
+	if cached != nil && cached.epoch == set.Epoch && cached.seed == set.Seed {
+		return cached.slots
+	}
+	
+	slots := BuildSchedule(set, d.Params().EpochLength)
+	
+	d.mu.Lock()
+	d.schedule = &epochSchedule{epoch: set.Epoch, seed: set.Seed, slots: slots}
+	d.mu.Unlock()
+	return slots
 }
 
-// ValidateBlock validates a block according to DPoS rules
-func (d *DPoS) ValidateBlock(block *core.Block) error {
+// ValidateProducer checks that producer is the active validator scheduled
+// for slot and returns it
+func (d *DPoS) ValidateProducer(blockNumber, slot uint64, producer types.Address) (*types.Validator, error) {
 	// Get expected validator
-	expectedValidator, err := d.GetBlockProducer(block.Header.Number)
+	expectedValidator, err := d.GetSlotProducer(blockNumber, slot)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	
 	// Check if block producer is correct
-	if block.Header.Validator != expectedValidator.Address {
-		return errors.New("invalid block producer")
+	if producer != expectedValidator.Address {
+		return nil, errors.New("invalid block producer")
 	}
 	
 	// Check if validator is active
 	if !expectedValidator.IsActive() {
-		return errors.New("validator is not active")
-	}
-	
-	// Validate block timestamp
-	if block.Header.Timestamp.Before(time.Now().Add(-time.Minute)) {
-		return errors.New("block timestamp too old")
+		return nil, errors.New("validator is not active")
 	}
 	
-	return nil
+	return expectedValidator, nil
 }
 
-// UpdateEpoch updates the current epoch and rotates validators
+// UpdateEpoch moves the current epoch to that of the latest block. The
+// validator sets themselves are scheduled in state by ScheduleValidatorSet.
 func (d *DPoS) UpdateEpoch(blockNumber uint64) {
-	if blockNumber%types.EpochLength == 0 {
-		d.mu.Lock()
-		d.currentEpoch++
-		d.mu.Unlock()
-		
-		// Select new validator set
-		d.SelectValidators()
-	}
+	epoch := d.EpochOf(blockNumber)
+	
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	d.currentEpoch = epoch
 }
 
 // GetValidator returns a validator by address
 func (d *DPoS) GetValidator(addr types.Address) (*types.Validator, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	
-	val, exists := d.validators[addr]
-	if !exists {
+	val, err := d.state.GetValidator(addr)
+	if err != nil {
 		return nil, errors.New("validator not found")
 	}
 	
 	return val, nil
 }
 
-// GetActiveValidators returns current active validators
+// GetActiveValidators returns the validators of the current epoch
 func (d *DPoS) GetActiveValidators() []*types.Validator {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+	set, err := d.GetValidatorSet(d.GetCurrentEpoch())
+	if err != nil {
+		return nil
+	}
 	
-	result := make([]*types.Validator, len(d.activeValidators))
-	copy(result, d.activeValidators)
+	result := make([]*types.Validator, 0, len(set.Validators))
+	for _, addr := range set.Validators {
+		if val, err := d.state.GetValidator(addr); err == nil {
+			result = append(result, val)
+		}
+	}
 	return result
 }
 
 // GetDelegation returns delegation information
 func (d *DPoS) GetDelegation(delegator, validator types.Address) (*types.Delegation, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	
-	if d.delegations[delegator] == nil {
-		return nil, errors.New("no delegations found")
-	}
-	
-	delegation, exists := d.delegations[delegator][validator]
-	if !exists {
+	delegation, err := d.state.GetDelegation(delegator, validator)
+	if err != nil {
 		return nil, errors.New("delegation not found")
 	}
 	
 	return delegation, nil
 }
 
+// GetValidatorDelegations returns the delegations to a validator
+func (d *DPoS) GetValidatorDelegations(validator types.Address) ([]*types.Delegation, error) {
+	return d.state.GetDelegationsByValidator(validator)
+}
+
 // GetTotalVotingPower returns total voting power of all validators
 func (d *DPoS) GetTotalVotingPower() *big.Int {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	
-	total := big.NewInt(0)
-	for _, val := range d.activeValidators {
-		total.Add(total, val.VotingPower)
+	_, total, err := d.GetVotingPowers(d.GetCurrentEpoch())
+	if err != nil {
+		return big.NewInt(0)
 	}
 	
 	return total
 }
 
+// GetVotingPowers returns the voting power of each member of an epoch's
+// validator set and their total. The powers are those recorded when the set
+// was selected, so they do not depend on the state at the current head.
+func (d *DPoS) GetVotingPowers(epoch uint64) (map[types.Address]*big.Int, *big.Int, error) {
+	set, err := d.GetValidatorSet(epoch)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(set.Powers) != len(set.Validators) {
+		return nil, nil, errors.New("validator set has no voting powers")
+	}
+	
+	powers := make(map[types.Address]*big.Int, len(set.Validators))
+	total := big.NewInt(0)
+	for i, addr := range set.Validators {
+		powers[addr] = new(big.Int).Set(set.Powers[i])
+		total.Add(total, set.Powers[i])
+	}
+	return powers, total, nil
+}
+
 // GetCurrentEpoch returns current epoch number
 func (d *DPoS) GetCurrentEpoch() uint64 {
 	d.mu.RLock()
@@ -0,0 +1,30 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/apex/pkg/types"
+)
+
+// Redelegation errors
+var (
+	ErrRedelegateSameValidator = errors.New("cannot redelegate to the source validator")
+	ErrTransitiveRedelegation  = errors.New("redelegation to source validator is still maturing")
+)
+
+// ValidateRedelegation checks that a delegator may move stake from src to
+// dst given its maturing redelegations. Stake that arrived at src through a
+// redelegation may not hop on before it completes, so it cannot escape
+// slashing of the validator it originally came from.
+func ValidateRedelegation(src, dst types.Address, pending []*types.Redelegation) error {
+	if src == dst {
+		return ErrRedelegateSameValidator
+	}
+	
+	for _, redelegation := range pending {
+		if redelegation.ValidatorDst == src {
+			return ErrTransitiveRedelegation
+		}
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package consensus
 
 import (
-	"errors"
 	"math/big"
 
 	"github.com/apex/pkg/types"
@@ -21,38 +20,45 @@ func NewRewardCalculator(dpos *DPoS) *RewardCalculator {
 
 // CalculateBlockReward calculates block production reward
 func (rc *RewardCalculator) CalculateBlockReward(blockNumber uint64) *big.Int {
-	// Base reward: 2 APX per block
-	baseReward := types.ToWei(2.0)
+	params := rc.dpos.Params()
 	
-	// Halving every 4 years (approximate)
-	blocksPerYear := uint64(365 * 24 * 60 * 60 / types.BlockTime)
-	halvingPeriod := blocksPerYear * 4
-	halvings := blockNumber / halvingPeriod
+	// Halve the initial reward every halving period
+	halvings := blockNumber / params.HalvingPeriod
 	
 	// Apply halving
-	reward := new(big.Int).Set(baseReward)
-	for i := uint64(0); i < halvings; i++ {
+	reward := new(big.Int).Set(params.InitialReward)
+	for i := uint64(0); i < halvings && reward.Sign() > 0; i++ {
 		reward.Div(reward, big.NewInt(2))
 	}
 	
-	// Minimum reward: 0.1 APX
-	minReward := types.ToWei(0.1)
-	if reward.Cmp(minReward) < 0 {
-		reward = minReward
+	// Never drop below the minimum reward
+	if reward.Cmp(params.MinimumReward) < 0 {
+		reward = new(big.Int).Set(params.MinimumReward)
 	}
 	
 	return reward
 }
 
-// DistributeBlockReward distributes block reward to validator and delegators
+// Reward is the part of a block reward paid to one account
+type Reward struct {
+	Recipient types.Address
+	Amount    *big.Int
+}
+
+// DistributeBlockReward splits the block reward and transaction fees of a
+// block between its producer and the delegators to it. Each delegator gets
+// its stake's share of the reward after commission; the validator keeps the
+// commission, the share of its self-stake and the rounding remainder. The
+// shares are added to the validator's and delegations' reward totals and
+// returned, the validator first, so the caller can credit the accounts.
 func (rc *RewardCalculator) DistributeBlockReward(
 	validatorAddr types.Address,
 	blockNumber uint64,
 	txFees *big.Int,
-) error {
+) ([]Reward, error) {
 	validator, err := rc.dpos.GetValidator(validatorAddr)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	
 	// Calculate total reward (block reward + tx fees)
@@ -62,44 +68,59 @@ func (rc *RewardCalculator) DistributeBlockReward(
 	// Calculate validator commission
 	commission := validator.CalculateCommission(totalReward)
 	
-	// Remaining reward for delegators
-	delegatorReward := new(big.Int).Sub(totalReward, commission)
-	
-	// Add commission to validator
-	validator.TotalRewards.Add(validator.TotalRewards, commission)
+	// Remaining reward for the stake bonded to the validator
+	stakeReward := new(big.Int).Sub(totalReward, commission)
 	
 	// Distribute to delegators proportionally
+	var delegatorRewards []Reward
 	if validator.VotingPower.Sign() > 0 {
-		rc.distributeToDelegate(validatorAddr, delegatorReward)
+		delegatorRewards, err = rc.distributeToDelegators(validator, stakeReward)
+		if err != nil {
+			return nil, err
+		}
 	}
 	
-	return nil
+	// The validator keeps whatever the delegators did not get
+	validatorReward := new(big.Int).Set(totalReward)
+	for _, reward := range delegatorRewards {
+		validatorReward.Sub(validatorReward, reward.Amount)
+	}
+	validator.TotalRewards.Add(validator.TotalRewards, validatorReward)
+	if err := rc.dpos.UpdateValidator(validator); err != nil {
+		return nil, err
+	}
+	
+	return append([]Reward{{Recipient: validatorAddr, Amount: validatorReward}}, delegatorRewards...), nil
 }
 
-// distributeToDelegate distributes rewards to delegators
-func (rc *RewardCalculator) distributeToDelegate(
-	validatorAddr types.Address,
+// distributeToDelegators gives every delegator to validator its stake's
+// share of totalReward
+func (rc *RewardCalculator) distributeToDelegators(
+	validator *types.Validator,
 	totalReward *big.Int,
-) {
-	validator, _ := rc.dpos.GetValidator(validatorAddr)
-	
-	// Get all delegations for this validator
-	rc.dpos.mu.RLock()
-	defer rc.dpos.mu.RUnlock()
-	
-	for delegatorAddr, delegations := range rc.dpos.delegations {
-		if delegation, exists := delegations[validatorAddr]; exists {
-			// Calculate delegator's share
-			share := new(big.Int).Mul(totalReward, delegation.Amount)
-			share.Div(share, validator.VotingPower)
-			
-			// Add to delegation rewards
-			delegation.AddRewards(share)
-			
-			// Update account (if integrated with state)
-			_ = delegatorAddr // Use delegatorAddr to update account state
+) ([]Reward, error) {
+	delegations, err := rc.dpos.GetValidatorDelegations(validator.Address)
+	if err != nil {
+		return nil, err
+	}
+	
+	rewards := make([]Reward, 0, len(delegations))
+	for _, delegation := range delegations {
+		// Calculate delegator's share
+		share := new(big.Int).Mul(totalReward, delegation.Amount)
+		share.Div(share, validator.VotingPower)
+		if share.Sign() == 0 {
+			continue
+		}
+		
+		// Add to delegation rewards
+		delegation.AddRewards(share)
+		if err := rc.dpos.UpdateDelegation(delegation); err != nil {
+			return nil, err
 		}
+		rewards = append(rewards, Reward{Recipient: delegation.Delegator, Amount: share})
 	}
+	return rewards, nil
 }
 
 // CalculateAPY calculates Annual Percentage Yield for staking
@@ -110,7 +131,7 @@ func (rc *RewardCalculator) CalculateAPY() float64 {
 	}
 	
 	// Approximate blocks per year
-	blocksPerYear := uint64(365 * 24 * 60 * 60 / types.BlockTime)
+	blocksPerYear := rc.dpos.Params().BlocksPerYear()
 	
 	// Average reward per block
 	avgReward := rc.CalculateBlockReward(0) // Use block 0 as reference
@@ -128,29 +149,7 @@ func (rc *RewardCalculator) CalculateAPY() float64 {
 	return apyFloat * 100
 }
 
-// ClaimRewards allows delegator to claim accumulated rewards
-func (rc *RewardCalculator) ClaimRewards(
-	delegatorAddr, validatorAddr types.Address,
-) (*big.Int, error) {
-	delegation, err := rc.dpos.GetDelegation(delegatorAddr, validatorAddr)
-	if err != nil {
-		return nil, err
-	}
-	
-	if delegation.Rewards.Sign() == 0 {
-		return nil, errors.New("no rewards to claim")
-	}
-	
-	// Get rewards amount
-	rewards := new(big.Int).Set(delegation.Rewards)
-	
-	// Reset delegation rewards
-	delegation.Rewards = big.NewInt(0)
-	
-	return rewards, nil
-}
-
-// GetDelegatorRewards returns accumulated rewards for a delegator
+// GetDelegatorRewards returns the rewards a delegation has been paid
 func (rc *RewardCalculator) GetDelegatorRewards(
 	delegatorAddr, validatorAddr types.Address,
 ) (*big.Int, error) {
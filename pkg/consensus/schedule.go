@@ -0,0 +1,88 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/types"
+)
+
+// SlotOf returns the slot a timestamp falls in. Slots are blockTime seconds
+// long and counted from the genesis time; one block may be produced per slot.
+func SlotOf(genesisTime, timestamp time.Time, blockTime uint64) uint64 {
+	if !timestamp.After(genesisTime) {
+		return 0
+	}
+	return uint64(timestamp.Sub(genesisTime) / (time.Duration(blockTime) * time.Second))
+}
+
+// SlotTime returns the start time of a slot
+func SlotTime(genesisTime time.Time, slot, blockTime uint64) time.Time {
+	return genesisTime.Add(time.Duration(slot*blockTime) * time.Second)
+}
+
+// BuildSchedule derives the producer of each of the slots of an epoch from
+// its validator set. Every validator gets a share of the slots proportional
+// to its weight, or an equal share if the set carries no weights, and the
+// slots are then shuffled with the set's seed. The result depends only on
+// the set, so every node derives the same schedule.
+func BuildSchedule(set *types.ValidatorSet, slots uint64) []types.Address {
+	n := len(set.Validators)
+	if n == 0 || slots == 0 {
+		return nil
+	}
+	
+	weights := make([]*big.Int, n)
+	total := big.NewInt(0)
+	for i := range weights {
+		weights[i] = big.NewInt(1)
+		if len(set.Weights) == n && set.Weights[i] != nil && set.Weights[i].Sign() > 0 {
+			weights[i] = set.Weights[i]
+		}
+		total.Add(total, weights[i])
+	}
+	
+	// Allocate slots by largest remainder
+	counts := make([]uint64, n)
+	remainders := make([]*big.Int, n)
+	allocated := uint64(0)
+	for i, weight := range weights {
+		quota := new(big.Int).Mul(weight, new(big.Int).SetUint64(slots))
+		count, remainder := new(big.Int).QuoRem(quota, total, new(big.Int))
+		counts[i] = count.Uint64()
+		remainders[i] = remainder
+		allocated += counts[i]
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+	for i := 0; allocated < slots; i++ {
+		counts[order[i%n]]++
+		allocated++
+	}
+	
+	schedule := make([]types.Address, 0, slots)
+	for i, count := range counts {
+		for j := uint64(0); j < count; j++ {
+			schedule = append(schedule, set.Validators[i])
+		}
+	}
+	
+	// Fisher-Yates shuffle driven by the seed
+	buf := make([]byte, len(set.Seed)+8)
+	copy(buf, set.Seed[:])
+	for i := len(schedule) - 1; i > 0; i-- {
+		binary.BigEndian.PutUint64(buf[len(set.Seed):], uint64(i))
+		hash := crypto.HashData(buf)
+		j := binary.BigEndian.Uint64(hash[:8]) % uint64(i+1)
+		schedule[i], schedule[j] = schedule[j], schedule[i]
+	}
+	return schedule
+}
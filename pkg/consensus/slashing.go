@@ -16,126 +16,152 @@ const (
 	SlashingReasonInvalidBlock
 )
 
-// SlashingEvent represents a slashing event
-type SlashingEvent struct {
-	Validator types.Address
-	Reason    SlashingReason
-	Amount    *big.Int
-	BlockNum  uint64
+// String returns the name recorded in slashing events
+func (r SlashingReason) String() string {
+	switch r {
+	case SlashingReasonDoubleSign:
+		return "double_sign"
+	case SlashingReasonDowntime:
+		return "downtime"
+	case SlashingReasonInvalidBlock:
+		return "invalid_block"
+	default:
+		return "unknown"
+	}
 }
 
-// Slasher handles validator slashing
+// Slash is the stake a slash took from one staker
+type Slash struct {
+	Staker types.Address
+	Amount *big.Int
+}
+
+// Slasher handles validator slashing. Slashing events are kept in state.
 type Slasher struct {
-	dpos   *DPoS
-	events []SlashingEvent
+	dpos *DPoS
 }
 
 // NewSlasher creates a new slasher
 func NewSlasher(dpos *DPoS) *Slasher {
 	return &Slasher{
-		dpos:   dpos,
-		events: make([]SlashingEvent, 0),
+		dpos: dpos,
 	}
 }
 
-// SlashValidator slashes a validator's stake
-func (s *Slasher) SlashValidator(
-	address types.Address,
-	reason SlashingReason,
-	blockNum uint64,
-) error {
-	validator, err := s.dpos.GetValidator(address)
-	if err != nil {
-		return err
-	}
-	
-	// Calculate slash amount based on reason
-	var slashPercentage float64
+// SlashFraction returns the fraction of stake, in basis points, slashed for
+// reason
+func SlashFraction(params *types.Params, reason SlashingReason) (uint64, error) {
 	switch reason {
 	case SlashingReasonDoubleSign:
-		slashPercentage = 0.05 // 5% slash for double signing
+		return params.SlashFractionDoubleSign, nil
 	case SlashingReasonDowntime:
-		slashPercentage = 0.01 // 1% slash for downtime
+		return params.SlashFractionDowntime, nil
 	case SlashingReasonInvalidBlock:
-		slashPercentage = 0.03 // 3% slash for invalid block
+		return params.SlashFractionInvalidBlock, nil
 	default:
-		return errors.New("unknown slashing reason")
+		return 0, errors.New("unknown slashing reason")
+	}
+}
+
+// SlashValidator slashes a validator's self-stake and every delegation to
+// it by the fraction for reason, lowers its voting power by the total and
+// jails it in the block at height. Double signing tombstones the validator,
+// jailing it for good. It returns the amount taken from each staker, the
+// validator itself first, so the caller can settle their accounts.
+func (s *Slasher) SlashValidator(address types.Address, reason SlashingReason, height uint64) ([]Slash, error) {
+	validator, err := s.dpos.GetValidator(address)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Calculate slash amount from voting power
-	slashAmount := new(big.Int).Mul(
-		validator.VotingPower,
-		big.NewInt(int64(slashPercentage*10000)),
-	)
-	slashAmount.Div(slashAmount, big.NewInt(10000))
-	
-	// Apply slash
-	validator.SubVotingPower(slashAmount)
+	// Calculate slash fraction (basis points) based on reason
+	slashFraction, err := SlashFraction(s.dpos.Params(), reason)
+	if err != nil {
+		return nil, err
+	}
 	
-	// Also slash self-stake proportionally
-	selfStakeSlash := new(big.Int).Mul(
-		validator.SelfStake,
-		big.NewInt(int64(slashPercentage*10000)),
-	)
-	selfStakeSlash.Div(selfStakeSlash, big.NewInt(10000))
+	// Slash self-stake
+	selfStakeSlash := fractionOf(validator.SelfStake, slashFraction)
 	validator.SelfStake.Sub(validator.SelfStake, selfStakeSlash)
+	slashes := []Slash{{Staker: address, Amount: selfStakeSlash}}
+	total := new(big.Int).Set(selfStakeSlash)
 	
-	// Record slashing event
-	event := SlashingEvent{
-		Validator: address,
-		Reason:    reason,
-		Amount:    slashAmount,
-		BlockNum:  blockNum,
+	// Slash every delegation by the same fraction
+	delegations, err := s.dpos.GetValidatorDelegations(address)
+	if err != nil {
+		return nil, err
 	}
-	s.events = append(s.events, event)
+	for _, delegation := range delegations {
+		amount := fractionOf(delegation.Amount, slashFraction)
+		if amount.Sign() == 0 {
+			continue
+		}
+		
+		delegation.Amount.Sub(delegation.Amount, amount)
+		if delegation.Amount.Sign() == 0 {
+			err = s.dpos.state.DeleteDelegation(delegation.Delegator, delegation.Validator)
+		} else {
+			err = s.dpos.UpdateDelegation(delegation)
+		}
+		if err != nil {
+			return nil, err
+		}
+		
+		slashes = append(slashes, Slash{Staker: delegation.Delegator, Amount: amount})
+		total.Add(total, amount)
+	}
+	
+	// Voting power keeps matching the stake bonded to the validator
+	validator.SubVotingPower(total)
 	
-	// Jail validator for serious offenses
-	if reason == SlashingReasonDoubleSign || reason == SlashingReasonInvalidBlock {
-		validator.Jailed = true
-		validator.Status = types.ValidatorStatusJailed
+	// Jail the validator; it starts a fresh signing window once unjailed
+	if reason == SlashingReasonDoubleSign {
+		validator.Tombstone()
+	} else {
+		validator.Jail(height + s.dpos.Params().DowntimeJailDuration)
+	}
+	validator.MissedBlocks = 0
+	if err := s.dpos.UpdateSigningInfo(types.NewSigningInfo(address)); err != nil {
+		return nil, err
 	}
 	
-	return nil
+	if err := s.dpos.UpdateValidator(validator); err != nil {
+		return nil, err
+	}
+	return slashes, nil
 }
 
-// DetectDoubleSign detects if validator signed multiple blocks at same height
-func (s *Slasher) DetectDoubleSign(
-	address types.Address,
-	block1Height, block2Height uint64,
-) error {
-	if block1Height == block2Height {
-		return s.SlashValidator(address, SlashingReasonDoubleSign, block1Height)
-	}
-	return nil
+// fractionOf returns fraction basis points of amount, rounded down
+func fractionOf(amount *big.Int, fraction uint64) *big.Int {
+	result := new(big.Int).Mul(amount, new(big.Int).SetUint64(fraction))
+	return result.Div(result, big.NewInt(10000))
 }
 
-// CheckDowntime checks validator downtime and applies penalties
-func (s *Slasher) CheckDowntime(address types.Address, missedBlocks uint64) error {
-	// Slash if missed too many blocks in a row
-	const downtimeThreshold = 50
-	if missedBlocks >= downtimeThreshold {
-		validator, err := s.dpos.GetValidator(address)
-		if err != nil {
-			return err
-		}
-		
-		return s.SlashValidator(address, SlashingReasonDowntime, validator.LastActiveEpoch)
+// RecordEvent stores a slashing event in state
+func (s *Slasher) RecordEvent(event *types.SlashingEvent) error {
+	return s.dpos.state.AddSlashingEvent(event)
+}
+
+// CheckDowntime reports whether a validator filled less than the minimum
+// share of the slots in its signing window. A validator is only judged once
+// its window is full.
+func (s *Slasher) CheckDowntime(address types.Address) bool {
+	params := s.dpos.Params()
+	info := s.dpos.GetSigningInfo(address)
+	if info.IndexOffset < params.SignedSlotsWindow {
+		return false
 	}
-	return nil
+	
+	signed := params.SignedSlotsWindow - info.MissedCount
+	return signed*10000 < params.MinSignedPerWindow*params.SignedSlotsWindow
 }
 
 // GetSlashingEvents returns all slashing events
-func (s *Slasher) GetSlashingEvents() []SlashingEvent {
-	return s.events
+func (s *Slasher) GetSlashingEvents() ([]*types.SlashingEvent, error) {
+	return s.dpos.state.GetAllSlashingEvents()
 }
 
 // GetValidatorSlashingHistory returns slashing history for a validator
-func (s *Slasher) GetValidatorSlashingHistory(address types.Address) []SlashingEvent {
-	var history []SlashingEvent
-	for _, event := range s.events {
-		if event.Validator == address {
-			history = append(history, event)
-		}
-	}
-	return history
+func (s *Slasher) GetValidatorSlashingHistory(address types.Address) ([]*types.SlashingEvent, error) {
+	return s.dpos.state.GetSlashingEvents(address)
 }
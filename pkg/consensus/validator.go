@@ -29,12 +29,11 @@ func (vm *ValidatorManager) CreateValidator(
 ) error {
 	// Validate commission rate (0-100%)
 	if commission > 10000 {
-		return errors.New("commission rate must be <= 100%")
+		return ErrCommissionTooHigh
 	}
 	
 	// Validate minimum stake
-	minStake := types.ToWei(float64(types.MinStakeAmount))
-	if selfStake.Cmp(minStake) < 0 {
+	if selfStake.Cmp(vm.dpos.Params().MinStake) < 0 {
 		return errors.New("insufficient self-stake for validator")
 	}
 	
@@ -45,6 +44,37 @@ func (vm *ValidatorManager) CreateValidator(
 	return vm.dpos.RegisterValidator(validator)
 }
 
+// CommissionUpdateInterval is the minimum time between commission changes
+const CommissionUpdateInterval = 24 * time.Hour
+
+// Commission errors
+var (
+	ErrCommissionTooHigh        = errors.New("commission rate must be <= 100%")
+	ErrCommissionUpdateTooSoon  = errors.New("commission can only be changed once per 24h")
+	ErrCommissionChangeTooLarge = errors.New("commission change exceeds max change rate")
+)
+
+// ValidateCommissionChange checks that a validator may change its commission
+// to rate at time now: at most maxChangeRate basis points at once, and no
+// sooner than CommissionUpdateInterval after the previous change
+func ValidateCommissionChange(validator *types.Validator, rate, maxChangeRate uint64, now time.Time) error {
+	if rate > 10000 {
+		return ErrCommissionTooHigh
+	}
+	if now.Sub(validator.CommissionUpdatedAt) < CommissionUpdateInterval {
+		return ErrCommissionUpdateTooSoon
+	}
+	
+	change := rate - validator.Commission
+	if rate < validator.Commission {
+		change = validator.Commission - rate
+	}
+	if change > maxChangeRate {
+		return ErrCommissionChangeTooLarge
+	}
+	return nil
+}
+
 // EditValidator updates validator metadata
 func (vm *ValidatorManager) EditValidator(
 	address types.Address,
@@ -57,17 +87,46 @@ func (vm *ValidatorManager) EditValidator(
 	
 	// Update commission if provided
 	if commission != nil {
-		if *commission > 10000 {
-			return errors.New("commission rate must be <= 100%")
+		now := time.Now()
+		if err := ValidateCommissionChange(validator, *commission, vm.dpos.Params().MaxCommissionChangeRate, now); err != nil {
+			return err
 		}
 		validator.Commission = *commission
+		validator.CommissionUpdatedAt = now
 	}
 	
+	return vm.dpos.UpdateValidator(validator)
+}
+
+// Jail errors
+var (
+	ErrValidatorNotJailed  = errors.New("validator is not jailed")
+	ErrValidatorTombstoned = errors.New("validator is tombstoned and cannot be unjailed")
+	ErrJailPeriodNotOver   = errors.New("jail period not completed")
+	ErrSelfStakeTooLow     = errors.New("self-stake below the minimum validator stake")
+)
+
+// ValidateUnjail checks that a validator may leave jail in the block at
+// height: it is jailed but not tombstoned, its jail period is over and its
+// self-stake still meets minStake
+func ValidateUnjail(validator *types.Validator, minStake *big.Int, height uint64) error {
+	if !validator.Jailed {
+		return ErrValidatorNotJailed
+	}
+	if validator.Tombstoned {
+		return ErrValidatorTombstoned
+	}
+	if height < validator.JailedUntil {
+		return ErrJailPeriodNotOver
+	}
+	if validator.SelfStake.Cmp(minStake) < 0 {
+		return ErrSelfStakeTooLow
+	}
 	return nil
 }
 
-// JailValidator jails a validator for misbehavior
-func (vm *ValidatorManager) JailValidator(address types.Address, reason string) error {
+// JailValidator jails a validator until block until
+func (vm *ValidatorManager) JailValidator(address types.Address, until uint64) error {
 	validator, err := vm.dpos.GetValidator(address)
 	if err != nil {
 		return err
@@ -77,81 +136,81 @@ func (vm *ValidatorManager) JailValidator(address types.Address, reason string)
 		return errors.New("validator already jailed")
 	}
 	
-	validator.Jailed = true
-	validator.JailTime = time.Now()
-	validator.Status = types.ValidatorStatusJailed
+	validator.Jail(until)
 	
-	return nil
+	return vm.dpos.UpdateValidator(validator)
 }
 
-// UnjailValidator unjails a validator
-func (vm *ValidatorManager) UnjailValidator(address types.Address) error {
+// UnjailValidator unjails a validator in the block at height
+func (vm *ValidatorManager) UnjailValidator(address types.Address, height uint64) error {
 	validator, err := vm.dpos.GetValidator(address)
 	if err != nil {
 		return err
 	}
 	
-	if !validator.Jailed {
-		return errors.New("validator is not jailed")
-	}
-	
-	// Check if jail period has passed (e.g., 24 hours)
-	jailDuration := 24 * time.Hour
-	if time.Since(validator.JailTime) < jailDuration {
-		return errors.New("jail period not completed")
+	if err := ValidateUnjail(validator, vm.dpos.Params().MinStake, height); err != nil {
+		return err
 	}
 	
-	validator.Jailed = false
-	validator.Status = types.ValidatorStatusActive
-	validator.MissedBlocks = 0
+	validator.Unjail()
 	
-	return nil
+	return vm.dpos.UpdateValidator(validator)
 }
 
-// IncrementMissedBlocks increments missed block count
-func (vm *ValidatorManager) IncrementMissedBlocks(address types.Address) error {
+// IncrementMissedBlocks records a slot of blockNumber's schedule that the
+// validator missed in its signing window
+func (vm *ValidatorManager) IncrementMissedBlocks(address types.Address, blockNumber uint64) error {
+	return vm.recordSlot(address, blockNumber, true)
+}
+
+// IncrementProducedBlocks records the slot of blockNumber the validator
+// filled in its signing window
+func (vm *ValidatorManager) IncrementProducedBlocks(address types.Address, blockNumber uint64) error {
+	return vm.recordSlot(address, blockNumber, false)
+}
+
+// recordSlot records a scheduled slot in the validator's signing window.
+// MissedBlocks mirrors the misses within the window.
+func (vm *ValidatorManager) recordSlot(address types.Address, blockNumber uint64, missed bool) error {
 	validator, err := vm.dpos.GetValidator(address)
 	if err != nil {
 		return err
 	}
 	
-	validator.MissedBlocks++
-	
-	// Auto-jail after threshold (e.g., 100 missed blocks)
-	const missedBlockThreshold = 100
-	if validator.MissedBlocks >= missedBlockThreshold && !validator.Jailed {
-		return vm.JailValidator(address, "excessive missed blocks")
+	// A jailed validator cannot produce, so its slots are not held against it
+	if validator.Jailed {
+		return nil
 	}
 	
-	return nil
-}
-
-// IncrementProducedBlocks increments produced block count
-func (vm *ValidatorManager) IncrementProducedBlocks(address types.Address) error {
-	validator, err := vm.dpos.GetValidator(address)
-	if err != nil {
+	info := vm.dpos.GetSigningInfo(address)
+	info.Record(missed, vm.dpos.Params().SignedSlotsWindow)
+	if err := vm.dpos.UpdateSigningInfo(info); err != nil {
 		return err
 	}
 	
-	validator.ProducedBlocks++
-	validator.LastActiveEpoch = vm.dpos.GetCurrentEpoch()
+	validator.MissedBlocks = info.MissedCount
+	if !missed {
+		validator.ProducedBlocks++
+		validator.LastActiveEpoch = vm.dpos.EpochOf(blockNumber)
+	}
 	
-	return nil
+	return vm.dpos.UpdateValidator(validator)
 }
 
-// CalculateUptime calculates validator uptime percentage
+// CalculateUptime calculates the percentage of the slots in a validator's
+// signing window that it filled
 func (vm *ValidatorManager) CalculateUptime(address types.Address) (float64, error) {
-	validator, err := vm.dpos.GetValidator(address)
-	if err != nil {
+	if _, err := vm.dpos.GetValidator(address); err != nil {
 		return 0, err
 	}
 	
-	total := validator.ProducedBlocks + validator.MissedBlocks
-	if total == 0 {
+	info := vm.dpos.GetSigningInfo(address)
+	recorded := info.Recorded(vm.dpos.Params().SignedSlotsWindow)
+	if recorded == 0 {
 		return 100.0, nil
 	}
 	
-	uptime := float64(validator.ProducedBlocks) / float64(total) * 100
+	uptime := float64(recorded-info.MissedCount) / float64(recorded) * 100
 	return uptime, nil
 }
 
@@ -166,6 +225,9 @@ func (vm *ValidatorManager) GetValidatorStats(address types.Address) (map[string
 	
 	return map[string]interface{}{
 		"address":         validator.Address.Hex(),
+		"moniker":         validator.Description.Moniker,
+		"website":         validator.Description.Website,
+		"details":         validator.Description.Details,
 		"voting_power":    types.FromWei(validator.VotingPower),
 		"self_stake":      types.FromWei(validator.SelfStake),
 		"commission":      float64(validator.Commission) / 100,
@@ -2,7 +2,6 @@ package core
 
 import (
 	"crypto/sha256"
-	"encoding/json"
 	"math/big"
 	"time"
 
@@ -16,16 +15,20 @@ type BlockHeader struct {
 	Timestamp       time.Time     `json:"timestamp"`
 	TransactionRoot types.Hash    `json:"transaction_root"`
 	StateRoot       types.Hash    `json:"state_root"`
+	ReceiptsRoot    types.Hash    `json:"receipts_root"`
+	EvidenceRoot    types.Hash    `json:"evidence_root"`
 	Validator       types.Address `json:"validator"`
 	Signature       types.Signature `json:"signature"`
 	GasUsed         uint64        `json:"gas_used"`
 	GasLimit        uint64        `json:"gas_limit"`
+	BaseFee         *big.Int      `json:"base_fee"`
 }
 
 // Block represents a blockchain block
 type Block struct {
 	Header       *BlockHeader   `json:"header"`
 	Transactions []*Transaction `json:"transactions"`
+	Evidence     []*Evidence    `json:"evidence"`
 	Hash         types.Hash     `json:"hash"`
 }
 
@@ -44,50 +47,96 @@ func NewBlock(number uint64, previousHash types.Hash, validator types.Address) *
 	}
 }
 
-// AddTransaction adds a transaction to the block
-func (b *Block) AddTransaction(tx *Transaction) bool {
+// AddTransaction adds an executed transaction to the block, charging the gas
+// it used against the block gas limit
+func (b *Block) AddTransaction(tx *Transaction, gasUsed uint64) bool {
 	// Check gas limit
-	txGas := tx.GasLimit
-	if b.Header.GasUsed+txGas > b.Header.GasLimit {
+	if b.Header.GasUsed+gasUsed > b.Header.GasLimit {
 		return false
 	}
 	
 	b.Transactions = append(b.Transactions, tx)
-	b.Header.GasUsed += txGas
+	b.Header.GasUsed += gasUsed
 	return true
 }
 
-// ComputeHash computes block hash
+// GasRemaining returns the gas left in the block
+func (b *Block) GasRemaining() uint64 {
+	return b.Header.GasLimit - b.Header.GasUsed
+}
+
+// ComputeHash computes block hash over the canonical header encoding,
+// including the producer signature
 func (b *Block) ComputeHash() types.Hash {
-	headerData, _ := json.Marshal(b.Header)
+	return b.Header.Hash()
+}
+
+// Hash returns the hash of the block with this header
+func (h *BlockHeader) Hash() types.Hash {
+	headerData, _ := h.MarshalBinary()
 	hash := sha256.Sum256(headerData)
 	var blockHash types.Hash
 	copy(blockHash[:], hash[:])
 	return blockHash
 }
 
+// SigningHash returns the hash signed by the block producer: the chain ID and
+// the canonical header encoding without the signature. Covering the chain ID
+// keeps a header signature, and evidence built from it, to one chain.
+func (h *BlockHeader) SigningHash(chainID string) types.Hash {
+	hash := sha256.Sum256(h.signingBytes(chainID))
+	var signingHash types.Hash
+	copy(signingHash[:], hash[:])
+	return signingHash
+}
+
 // ComputeTransactionRoot computes merkle root of transactions
 func (b *Block) ComputeTransactionRoot() types.Hash {
-	if len(b.Transactions) == 0 {
-		return types.Hash{}
+	return MerkleRoot(b.transactionLeaves())
+}
+
+// GetTransactionProof returns the inclusion proof of the transaction at index
+// against the block's transaction root
+func (b *Block) GetTransactionProof(index int) (*MerkleProof, error) {
+	return ComputeMerkleProof(b.transactionLeaves(), index)
+}
+
+// VerifyTransactionProof checks that txHash is included in the block with the
+// given header, without needing the block body
+func VerifyTransactionProof(header *BlockHeader, txHash types.Hash, proof *MerkleProof) bool {
+	return VerifyMerkleProof(header.TransactionRoot, txHash[:], proof)
+}
+
+// transactionLeaves returns the transaction hashes used as Merkle leaves
+func (b *Block) transactionLeaves() [][]byte {
+	leaves := make([][]byte, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		leaves[i] = tx.Hash[:]
 	}
-	
-	// Simple implementation - hash all transaction hashes together
-	var allHashes []byte
-	for _, tx := range b.Transactions {
-		allHashes = append(allHashes, tx.Hash[:]...)
+	return leaves
+}
+
+// ComputeEvidenceRoot computes merkle root of the included evidence
+func (b *Block) ComputeEvidenceRoot() types.Hash {
+	leaves := make([][]byte, len(b.Evidence))
+	for i, evidence := range b.Evidence {
+		hash := evidence.Hash()
+		leaves[i] = hash[:]
 	}
-	
-	hash := sha256.Sum256(allHashes)
-	var root types.Hash
-	copy(root[:], hash[:])
-	return root
+	return MerkleRoot(leaves)
+}
+
+// AddEvidence adds double-sign evidence to the block
+func (b *Block) AddEvidence(evidence *Evidence) {
+	b.Evidence = append(b.Evidence, evidence)
 }
 
 // Finalize finalizes the block (compute roots and hash)
-func (b *Block) Finalize(stateRoot types.Hash) {
+func (b *Block) Finalize(stateRoot, receiptsRoot types.Hash) {
 	b.Header.TransactionRoot = b.ComputeTransactionRoot()
+	b.Header.EvidenceRoot = b.ComputeEvidenceRoot()
 	b.Header.StateRoot = stateRoot
+	b.Header.ReceiptsRoot = receiptsRoot
 	b.Hash = b.ComputeHash()
 }
 
@@ -108,12 +157,48 @@ func (b *Block) Validate() error {
 	if b.Header.GasUsed > b.Header.GasLimit {
 		return ErrGasLimitExceeded
 	}
+	if b.Header.BaseFee == nil || b.Header.BaseFee.Sign() < 0 {
+		return ErrInvalidBaseFee
+	}
 	
-	// Validate transactions
+	// Validate transactions; each gas limit must fit in the block
 	for _, tx := range b.Transactions {
 		if err := tx.Validate(); err != nil {
 			return err
 		}
+		if tx.Hash != tx.ComputeHash() {
+			return ErrInvalidTxHash
+		}
+		if tx.GasLimit > b.Header.GasLimit {
+			return ErrGasLimitExceeded
+		}
+	}
+	
+	// Check transaction root
+	if b.Header.TransactionRoot != b.ComputeTransactionRoot() {
+		return ErrInvalidTxRoot
+	}
+	
+	// Validate evidence; each offence may appear once
+	if len(b.Evidence) > MaxBlockEvidence {
+		return ErrTooMuchEvidence
+	}
+	seen := make(map[types.Hash]bool, len(b.Evidence))
+	for _, evidence := range b.Evidence {
+		if err := evidence.ValidateBasic(); err != nil {
+			return err
+		}
+		if evidence.Height() >= b.Header.Number {
+			return ErrInvalidEvidence
+		}
+		key := evidence.offenceKey()
+		if seen[key] {
+			return ErrDuplicateEvidence
+		}
+		seen[key] = true
+	}
+	if b.Header.EvidenceRoot != b.ComputeEvidenceRoot() {
+		return ErrInvalidEvidenceRoot
 	}
 	
 	// Check signature
@@ -121,6 +206,11 @@ func (b *Block) Validate() error {
 		return ErrMissingSignature
 	}
 	
+	// Check hash
+	if b.Hash != b.ComputeHash() {
+		return ErrInvalidBlockHash
+	}
+	
 	return nil
 }
 
@@ -145,9 +235,33 @@ var (
 	ErrInvalidTxValue    = &BlockError{msg: "invalid transaction value"}
 	ErrInvalidGasLimit   = &BlockError{msg: "invalid gas limit"}
 	ErrInvalidGasPrice   = &BlockError{msg: "invalid gas price"}
+	ErrInvalidTip        = &BlockError{msg: "invalid priority fee"}
+	ErrTipAboveFeeCap    = &BlockError{msg: "priority fee above max fee"}
+	ErrIntrinsicGas      = &BlockError{msg: "gas limit below intrinsic gas"}
+	ErrInvalidTxType     = &BlockError{msg: "invalid transaction type"}
 	ErrMissingSignature  = &BlockError{msg: "missing signature"}
+	ErrInvalidSignature  = &BlockError{msg: "invalid signature"}
+	ErrInvalidSender     = &BlockError{msg: "signature does not match sender"}
+	ErrInvalidChainID    = &BlockError{msg: "invalid chain id"}
 	ErrInvalidBlockHash  = &BlockError{msg: "invalid block hash"}
+	ErrInvalidTxHash     = &BlockError{msg: "invalid transaction hash"}
+	ErrInvalidTxRoot     = &BlockError{msg: "invalid transaction root"}
+	ErrInvalidNumber     = &BlockError{msg: "invalid block number"}
+	ErrInvalidParent     = &BlockError{msg: "invalid previous hash"}
+	ErrUnknownParent     = &BlockError{msg: "unknown parent block"}
+	ErrKnownBlock        = &BlockError{msg: "block already known"}
+	ErrReorgBelowFinalized = &BlockError{msg: "reorg below finalized height"}
+	ErrUnknownValidatorSet = &BlockError{msg: "validator set of block not selected before fork"}
+	ErrInvalidTimestamp  = &BlockError{msg: "block timestamp not after parent"}
+	ErrInvalidSlot       = &BlockError{msg: "block slot not after parent slot"}
 	ErrInvalidValidator  = &BlockError{msg: "invalid validator"}
+	ErrInvalidBlockSig   = &BlockError{msg: "invalid block signature"}
+	ErrInvalidStateRoot  = &BlockError{msg: "invalid state root"}
+	ErrInvalidReceipts   = &BlockError{msg: "invalid receipts root"}
+	ErrInvalidGasUsed    = &BlockError{msg: "invalid gas used"}
+	ErrInvalidBaseFee    = &BlockError{msg: "invalid base fee"}
+	ErrInvalidEvidenceRoot = &BlockError{msg: "invalid evidence root"}
+	ErrTooMuchEvidence   = &BlockError{msg: "too much evidence in block"}
 )
 
 type BlockError struct {
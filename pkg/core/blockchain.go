@@ -3,6 +3,8 @@ package core
 import (
 	"crypto/ecdsa"
 	"errors"
+	"fmt"
+	"math/big"
 	"sync"
 	"time"
 
@@ -14,86 +16,236 @@ import (
 
 // Blockchain represents the main blockchain
 type Blockchain struct {
-	blocks       []*Block
-	blocksByHash map[types.Hash]*Block
+	chainID      string
+	blocks       []*Block                                // canonical chain, indexed by number
+	blocksByHash map[types.Hash]*Block                   // all known blocks, including side chains
+	finalized    uint64                                  // blocks at or below this height are never reverted
+	precommits   map[uint64]map[types.Address]*Precommit // pending precommits by height and validator
+	evidencePool map[types.Hash]*Evidence                // double-sign evidence waiting for inclusion
+	txPool       TxPool
+	headHandlers []func(*Block)                          // called with the new head after AddBlock moves it
 	stateDB      *storage.StateDB
 	blockStore   *storage.BlockStore
 	dpos         *consensus.DPoS
 	rewardCalc   *consensus.RewardCalculator
 	slasher      *consensus.Slasher
+	validatorMgr *consensus.ValidatorManager
 	executor     *Executor
 	mu           sync.RWMutex
 }
 
-// NewBlockchain creates a new blockchain
+// NewBlockchain creates a new blockchain. If the block store already holds a
+// chain, its canonical blocks are loaded and consensus state is rebuilt from
+// the state database.
 func NewBlockchain(
+	chainID string,
 	stateDB *storage.StateDB,
 	blockStore *storage.BlockStore,
 	dpos *consensus.DPoS,
-) *Blockchain {
+) (*Blockchain, error) {
 	bc := &Blockchain{
+		chainID:      chainID,
 		blocks:       make([]*Block, 0),
 		blocksByHash: make(map[types.Hash]*Block),
+		precommits:   make(map[uint64]map[types.Address]*Precommit),
+		evidencePool: make(map[types.Hash]*Evidence),
 		stateDB:      stateDB,
 		blockStore:   blockStore,
 		dpos:         dpos,
 		rewardCalc:   consensus.NewRewardCalculator(dpos),
 		slasher:      consensus.NewSlasher(dpos),
+		validatorMgr: consensus.NewValidatorManager(dpos),
 	}
 	
 	bc.executor = NewExecutor(bc, stateDB)
 	
-	return bc
+	if err := bc.loadChain(); err != nil {
+		return nil, err
+	}
+	
+	return bc, nil
+}
+
+// loadChain loads the persisted canonical chain and restores the DPoS epoch. An empty store is left for InitGenesis.
+func (bc *Blockchain) loadChain() error {
+	head, err := bc.blockStore.GetLatestBlockNumber()
+	if err == storage.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	
+	for number := uint64(0); number <= head; number++ {
+		block, err := bc.blockStore.GetBlockByNumber(number)
+		if err != nil {
+			return fmt.Errorf("load block %d: %w", number, err)
+		}
+		if number > 0 && block.Header.PreviousHash != bc.blocks[number-1].Hash {
+			return fmt.Errorf("load block %d: %w", number, ErrInvalidParent)
+		}
+		bc.blocks = append(bc.blocks, block)
+		bc.blocksByHash[block.Hash] = block
+	}
+	
+	// The committed state must be the state after the head block
+	stateRoot, err := bc.stateDB.GetStateRoot()
+	if err != nil {
+		return err
+	}
+	if stateRoot != bc.latestBlock().Header.StateRoot {
+		return ErrStateMismatch
+	}
+	
+	params, err := bc.stateDB.GetParams()
+	if err != nil {
+		return fmt.Errorf("load params: %w", err)
+	}
+	bc.dpos.SetParams(params)
+	
+	bc.dpos.LoadState(head / params.EpochLength)
+	
+	finalized, err := bc.blockStore.GetFinalizedHeight()
+	if err != nil && err != storage.ErrKeyNotFound {
+		return err
+	}
+	if finalized <= head {
+		bc.finalized = finalized
+	}
+	
+	return nil
 }
 
-// InitGenesis initializes blockchain with genesis block
-func (bc *Blockchain) InitGenesis(genesisValidators []*types.Validator, genesisAccounts []*types.Account) error {
+// InitGenesis initializes the blockchain with the genesis block. If a chain
+// is already stored, it only checks that its genesis block matches the given
+// genesis and returns ErrGenesisMismatch otherwise.
+func (bc *Blockchain) InitGenesis(
+	genesisTime time.Time,
+	params *types.Params,
+	genesisValidators []*types.Validator,
+	genesisAccounts []*types.Account,
+) error {
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 	
-	// Create genesis block
-	genesis := NewBlock(0, types.Hash{}, types.Address{})
-	genesis.Header.Timestamp = time.Now()
+	if err := params.Validate(); err != nil {
+		return err
+	}
 	
-	// Initialize validators
-	for _, validator := range genesisValidators {
-		if err := bc.dpos.RegisterValidator(validator); err != nil {
-			return err
-		}
-		if err := bc.stateDB.SetValidator(validator); err != nil {
-			return err
-		}
+	if len(bc.blocks) > 0 {
+		return bc.verifyGenesis(genesisTime, params, genesisValidators, genesisAccounts)
 	}
 	
-	// Initialize accounts
-	for _, account := range genesisAccounts {
-		if err := bc.stateDB.SetAccount(account); err != nil {
-			return err
-		}
+	// Create genesis block
+	genesis, err := buildGenesis(bc.stateDB, genesisTime, params, genesisValidators, genesisAccounts)
+	if err != nil {
+		return err
 	}
 	
-	// Select initial validator set
-	bc.dpos.SelectValidators()
+	bc.dpos.SetParams(params)
 	
-	// Finalize genesis block
-	stateRoot, _ := bc.stateDB.GetStateRoot()
-	genesis.Finalize(stateRoot)
+	// Commit the genesis state in one batch with the genesis block, like
+	// connectBlock does for every later block
+	ops, err := bc.blockStore.ConnectOps(genesis, nil)
+	if err != nil {
+		bc.stateDB.Discard()
+		return err
+	}
+	if err := bc.stateDB.CommitBlock(genesis.Hash, ops...); err != nil {
+		bc.stateDB.Discard()
+		return err
+	}
 	
-	// Store genesis block
 	bc.blocks = append(bc.blocks, genesis)
 	bc.blocksByHash[genesis.Hash] = genesis
+	return nil
+}
+
+// verifyGenesis rebuilds the genesis block in a scratch database and compares
+// it with the stored one. The caller must hold bc.mu.
+func (bc *Blockchain) verifyGenesis(
+	genesisTime time.Time,
+	params *types.Params,
+	genesisValidators []*types.Validator,
+	genesisAccounts []*types.Account,
+) error {
+	db, err := storage.NewMemoryDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
 	
-	if err := bc.blockStore.PutBlock(genesis); err != nil {
+	stateDB, err := storage.NewStateDB(db)
+	if err != nil {
+		return err
+	}
+	genesis, err := buildGenesis(stateDB, genesisTime, params, genesisValidators, genesisAccounts)
+	if err != nil {
 		return err
 	}
 	
+	if genesis.Hash != bc.blocks[0].Hash {
+		return ErrGenesisMismatch
+	}
 	return nil
 }
 
-// ProduceBlock produces a new block (called by validator)
+// buildGenesis writes the genesis allocation to stateDB without committing
+// it and returns the resulting genesis block. The block only depends on its
+// arguments, so every node derives the same genesis hash.
+func buildGenesis(
+	stateDB *storage.StateDB,
+	genesisTime time.Time,
+	params *types.Params,
+	genesisValidators []*types.Validator,
+	genesisAccounts []*types.Account,
+) (*Block, error) {
+	genesis := NewBlock(0, types.Hash{}, types.Address{})
+	genesis.Header.Timestamp = genesisTime.UTC()
+	genesis.Header.BaseFee = new(big.Int).Set(InitialBaseFee)
+	
+	if err := stateDB.SetParams(params); err != nil {
+		return nil, err
+	}
+	
+	for _, validator := range genesisValidators {
+		validator.CreatedAt = genesis.Header.Timestamp
+		validator.CommissionUpdatedAt = genesis.Header.Timestamp
+		if err := stateDB.SetValidator(validator); err != nil {
+			return nil, err
+		}
+	}
+	
+	for _, account := range genesisAccounts {
+		if err := stateDB.SetAccount(account); err != nil {
+			return nil, err
+		}
+	}
+	
+	// The genesis validators produce blocks until the first selected set
+	// takes over
+	for _, set := range consensus.GenesisValidatorSets(genesisValidators, params) {
+		if err := stateDB.SetValidatorSet(set); err != nil {
+			return nil, err
+		}
+	}
+	
+	stateRoot, err := stateDB.GetStateRoot()
+	if err != nil {
+		return nil, err
+	}
+	genesis.Finalize(stateRoot, types.Hash{})
+	
+	return genesis, nil
+}
+
+// ProduceBlock produces a new block for the slot containing timestamp
+// (called by validator). It leaves the chain and state untouched; the
+// returned block is applied with AddBlock.
 func (bc *Blockchain) ProduceBlock(
 	validatorKey *ecdsa.PrivateKey,
 	transactions []*Transaction,
+	timestamp time.Time,
 ) (*Block, error) {
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
@@ -101,9 +253,16 @@ func (bc *Blockchain) ProduceBlock(
 	// Get validator address
 	validatorAddr := crypto.PublicKeyToAddress(&validatorKey.PublicKey)
 	
-	// Check if validator should produce this block
-	currentHeight := bc.GetHeight()
-	expectedValidator, err := bc.dpos.GetBlockProducer(currentHeight + 1)
+	// Get previous block
+	currentHeight := bc.height()
+	previousBlock := bc.latestBlock()
+	
+	// Check if validator should produce in this slot
+	slot := bc.slotOf(timestamp)
+	if slot <= bc.slotOf(previousBlock.Header.Timestamp) {
+		return nil, errors.New("slot already has a block")
+	}
+	expectedValidator, err := bc.dpos.GetSlotProducer(currentHeight+1, slot)
 	if err != nil {
 		return nil, err
 	}
@@ -111,41 +270,57 @@ func (bc *Blockchain) ProduceBlock(
 	if expectedValidator.Address != validatorAddr {
 		return nil, errors.New("not your turn to produce block")
 	}
-	
-	// Get previous block
-	previousBlock := bc.GetLatestBlock()
+	if !expectedValidator.IsActive() {
+		return nil, errors.New("validator is not active")
+	}
 	
 	// Create new block
 	block := NewBlock(currentHeight+1, previousBlock.Hash, validatorAddr)
-	
-	// Add transactions
-	totalFees := types.ToWei(0)
+	block.Header.Timestamp = timestamp.UTC()
+	block.Header.BaseFee = CalcBaseFee(previousBlock.Header)
+	
+	// Execute evidence and transactions speculatively; the state changes
+	// are discarded once the roots are known and re-applied when the block
+	// is added
+	defer bc.stateDB.Discard()
+	for _, evidence := range bc.pendingEvidence() {
+		if len(block.Evidence) == MaxBlockEvidence {
+			break
+		}
+		if err := bc.executor.ApplyEvidence(evidence, block.Header); err != nil {
+			continue
+		}
+		block.AddEvidence(evidence)
+	}
+	receipts := make([]*TxReceipt, 0, len(transactions))
 	for _, tx := range transactions {
-		if block.AddTransaction(tx) {
-			// Calculate fees
-			fee := tx.GasPrice
-			totalFees.Add(totalFees, fee)
+		// The whole gas limit must fit since that much may be used
+		if tx.GasLimit > block.GasRemaining() {
+			continue
+		}
+		receipt, err := bc.executor.ExecuteTransaction(tx, block.Header, len(block.Transactions))
+		if err != nil {
+			// Invalid transactions are left out of the block
+			continue
 		}
+		block.AddTransaction(tx, receipt.GasUsed)
+		receipts = append(receipts, receipt)
 	}
-	
-	// Execute transactions and update state
-	if err := bc.executor.ExecuteBlock(block); err != nil {
+	if err := bc.endBlock(block.Header, receipts); err != nil {
 		return nil, err
 	}
 	
-	// Distribute block rewards
-	if err := bc.rewardCalc.DistributeBlockReward(validatorAddr, block.Header.Number, totalFees); err != nil {
+	// Get state root
+	stateRoot, err := bc.stateDB.GetStateRoot()
+	if err != nil {
 		return nil, err
 	}
 	
-	// Get state root
-	stateRoot, _ := bc.stateDB.GetStateRoot()
-	
 	// Finalize block
-	block.Finalize(stateRoot)
+	block.Finalize(stateRoot, ComputeReceiptsRoot(receipts))
 	
 	// Sign block
-	signature, err := crypto.SignHash(block.Hash, validatorKey)
+	signature, err := crypto.SignHash(block.Header.SigningHash(bc.chainID), validatorKey)
 	if err != nil {
 		return nil, err
 	}
@@ -154,73 +329,303 @@ func (bc *Blockchain) ProduceBlock(
 	return block, nil
 }
 
-// AddBlock adds a validated block to the chain
+// AddBlock adds a block received from a producer or peer. A block extending
+// the head is applied directly; a block on a side chain is stored and
+// triggers a reorg if fork choice prefers its branch.
 func (bc *Blockchain) AddBlock(block *Block) error {
 	bc.mu.Lock()
-	defer bc.mu.Unlock()
-	
-	// Validate block
-	// Validate with DPoS
-if err := bc.dpos.ValidateBlock(block.Header.Number, block.Header.Validator, block.Header.Timestamp); err != nil {
+	head := bc.latestBlock()
+	err := bc.addBlock(block)
+	newHead := bc.latestBlock()
+	handlers := bc.headHandlers
+	bc.mu.Unlock()
+	
+	if newHead != head {
+		for _, handler := range handlers {
+			handler(newHead)
+		}
+	}
 	return err
 }
+
+// addBlock validates a block and connects or stores it. The caller must hold
+// bc.mu.
+func (bc *Blockchain) addBlock(block *Block) error {
+	if block == nil || block.Header == nil {
+		return ErrInvalidBlockHash
+	}
+	if _, known := bc.blocksByHash[block.Hash]; known {
+		return ErrKnownBlock
+	}
+	parent, exists := bc.blocksByHash[block.Header.PreviousHash]
+	if !exists {
+		return ErrUnknownParent
+	}
 	
-	// Execute block
-	if err := bc.executor.ExecuteBlock(block); err != nil {
+	// A block extending the head is validated against the current state, a
+	// side chain block against the validator set of its epoch
+	extendsHead := parent == bc.latestBlock()
+	if extendsHead {
+		if err := bc.validateBlock(block, parent); err != nil {
+			return err
+		}
+	} else if err := bc.validateSideBlock(block, parent); err != nil {
 		return err
 	}
 	
-	// Store block
-	bc.blocks = append(bc.blocks, block)
-	bc.blocksByHash[block.Hash] = block
+	// A second block signed by the producer at this height is evidence
+	bc.detectDoubleSign(block)
+	
+	// Extend the canonical chain
+	if extendsHead {
+		if err := bc.connectBlock(block); err != nil {
+			return err
+		}
+		bc.blocksByHash[block.Hash] = block
+		return nil
+	}
 	
+	// Store the side chain block and switch to its branch if it is preferred
+	if parent.Header.Number < bc.finalized {
+		return ErrReorgBelowFinalized
+	}
 	if err := bc.blockStore.PutBlock(block); err != nil {
 		return err
 	}
+	bc.blocksByHash[block.Hash] = block
+	
+	if bc.preferBranch(block) {
+		return bc.reorg(block)
+	}
+	return nil
+}
+
+// connectBlock executes a block on top of the head, commits its state and
+// makes it the new head. The caller must hold bc.mu.
+func (bc *Blockchain) connectBlock(block *Block) error {
+	// Execute block and check the results against the header
+	receipts, err := bc.applyBlock(block)
+	if err != nil {
+		bc.stateDB.Discard()
+		return err
+	}
+	
+	// Commit the state together with the block and its receipts, so the
+	// stored state never gets ahead of the canonical chain
+	for _, receipt := range receipts {
+		receipt.BlockHash = block.Hash
+	}
+	ops, err := bc.blockStore.ConnectOps(block, receipts)
+	if err != nil {
+		bc.stateDB.Discard()
+		return err
+	}
+	if err := bc.stateDB.CommitBlock(block.Hash, ops...); err != nil {
+		bc.stateDB.Discard()
+		return err
+	}
+	bc.blocks = append(bc.blocks, block)
 	
 	// Update epoch if needed
 	bc.dpos.UpdateEpoch(block.Header.Number)
 	
+	bc.pruneEvidence()
+	
 	return nil
 }
 
-// ValidateBlock validates a block
+// disconnectBlock reverts the head block's state and removes it from the
+// canonical chain. The caller must hold bc.mu.
+func (bc *Blockchain) disconnectBlock(block *Block) error {
+	ops, err := bc.blockStore.DisconnectOps(block)
+	if err != nil {
+		return err
+	}
+	if err := bc.stateDB.RevertBlock(block.Hash, ops...); err != nil {
+		return err
+	}
+	
+	bc.blocks = bc.blocks[:len(bc.blocks)-1]
+	bc.dpos.UpdateEpoch(bc.latestBlock().Header.Number)
+	
+	// Evidence of the reverted block can be included again
+	for _, evidence := range block.Evidence {
+		bc.evidencePool[evidence.Hash()] = evidence
+	}
+	return nil
+}
+
+// applyBlock executes a block and its end-block transitions on top of the
+// current state and verifies gas used, receipts root and state root against
+// its header
+func (bc *Blockchain) applyBlock(block *Block) ([]*TxReceipt, error) {
+	receipts, err := bc.executor.ExecuteBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	if err := bc.endBlock(block.Header, receipts); err != nil {
+		return nil, err
+	}
+	
+	var gasUsed uint64
+	for _, receipt := range receipts {
+		gasUsed += receipt.GasUsed
+	}
+	if gasUsed != block.Header.GasUsed {
+		return nil, ErrInvalidGasUsed
+	}
+	
+	if ComputeReceiptsRoot(receipts) != block.Header.ReceiptsRoot {
+		return nil, ErrInvalidReceipts
+	}
+	
+	stateRoot, err := bc.stateDB.GetStateRoot()
+	if err != nil {
+		return nil, err
+	}
+	if stateRoot != block.Header.StateRoot {
+		return nil, ErrInvalidStateRoot
+	}
+	
+	return receipts, nil
+}
+
+// ValidateBlock validates a block against its parent. A block that does not
+// extend the head is checked like a side chain block in AddBlock.
 func (bc *Blockchain) ValidateBlock(block *Block) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	
+	if block == nil || block.Header == nil {
+		return ErrInvalidBlockHash
+	}
+	parent, exists := bc.blocksByHash[block.Header.PreviousHash]
+	if !exists {
+		return ErrUnknownParent
+	}
+	if parent != bc.latestBlock() {
+		return bc.validateSideBlock(block, parent)
+	}
+	return bc.validateBlock(block, parent)
+}
+
+// validateBlock checks a block's structure, its linkage to the parent, its
+// producer and the producer signature. The producer is checked against the
+// current state, which must be the state after previousBlock. The caller
+// must hold bc.mu.
+func (bc *Blockchain) validateBlock(block *Block, previousBlock *Block) error {
+	if err := bc.validateHeader(block, previousBlock); err != nil {
+		return err
+	}
+	
+	// Check the producer is scheduled for this slot
+	slot := bc.slotOf(block.Header.Timestamp)
+	validator, err := bc.dpos.ValidateProducer(block.Header.Number, slot, block.Header.Validator)
+	if err != nil {
+		return ErrInvalidValidator
+	}
+	
+	return bc.verifyProducerSignature(block, validator)
+}
+
+// validateSideBlock checks a block that does not extend the head before it
+// is stored: its header, and that it was signed by the producer of its slot
+// in the validator set of its epoch. The set must have been selected on the
+// history both branches share; whether the producer is still active depends
+// on the state at the parent and is checked when a reorg connects the block.
+// The caller must hold bc.mu.
+func (bc *Blockchain) validateSideBlock(block *Block, parent *Block) error {
+	if err := bc.validateHeader(block, parent); err != nil {
+		return err
+	}
+	
+	_, ancestor := bc.branchFrom(parent)
+	if ancestor == nil {
+		return ErrUnknownParent
+	}
+	if !bc.dpos.SelectedBy(block.Header.Number, ancestor.Header.Number) {
+		return ErrUnknownValidatorSet
+	}
+	
+	slot := bc.slotOf(block.Header.Timestamp)
+	validator, err := bc.dpos.GetSlotProducer(block.Header.Number, slot)
+	if err != nil || validator.Address != block.Header.Validator {
+		return ErrInvalidValidator
+	}
+	return bc.verifyProducerSignature(block, validator)
+}
+
+// verifyProducerSignature checks a block's signature against the registered
+// key of its producer
+func (bc *Blockchain) verifyProducerSignature(block *Block, validator *types.Validator) error {
+	pubKey, err := crypto.BytesToPublicKey(validator.PublicKey)
+	if err != nil {
+		return ErrInvalidBlockSig
+	}
+	if !crypto.VerifyHashSignature(block.Header.SigningHash(bc.chainID), block.Header.Signature, pubKey) {
+		return ErrInvalidBlockSig
+	}
+	return nil
+}
+
+// validateHeader checks a block's structure and its linkage to the parent,
+// which do not depend on state. The caller must hold bc.mu.
+func (bc *Blockchain) validateHeader(block *Block, previousBlock *Block) error {
 	// Basic validation
 	if err := block.Validate(); err != nil {
 		return err
 	}
 	
 	// Check block number
-	expectedHeight := bc.GetHeight() + 1
-	if block.Header.Number != expectedHeight {
-		return errors.New("invalid block number")
+	if block.Header.Number != previousBlock.Header.Number+1 {
+		return ErrInvalidNumber
 	}
 	
 	// Check previous hash
-	previousBlock := bc.GetLatestBlock()
 	if block.Header.PreviousHash != previousBlock.Hash {
-		return errors.New("invalid previous hash")
+		return ErrInvalidParent
 	}
 	
-	// Validate with DPoS
-	if err := bc.dpos.ValidateBlock(block); err != nil {
-		return err
+	// Check timestamp; one block per slot
+	if !block.Header.Timestamp.After(previousBlock.Header.Timestamp) {
+		return ErrInvalidTimestamp
+	}
+	slot := bc.slotOf(block.Header.Timestamp)
+	if slot <= bc.slotOf(previousBlock.Header.Timestamp) {
+		return ErrInvalidSlot
+	}
+	
+	// Check the base fee follows from the parent's gas usage
+	if block.Header.BaseFee.Cmp(CalcBaseFee(previousBlock.Header)) != 0 {
+		return ErrInvalidBaseFee
 	}
 	
 	return nil
 }
 
+// slotOf returns the slot of a timestamp, counted from the genesis block.
+// The caller must hold bc.mu.
+func (bc *Blockchain) slotOf(timestamp time.Time) uint64 {
+	return consensus.SlotOf(bc.blocks[0].Header.Timestamp, timestamp, bc.dpos.Params().BlockTime)
+}
+
 // GetLatestBlock returns the latest block
 func (bc *Blockchain) GetLatestBlock() *Block {
 	bc.mu.RLock()
 	defer bc.mu.RUnlock()
 	
+	return bc.latestBlock()
+}
+
+// GetGenesisHash returns the hash of the genesis block
+func (bc *Blockchain) GetGenesisHash() types.Hash {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	
 	if len(bc.blocks) == 0 {
-		return nil
+		return types.Hash{}
 	}
-	
-	return bc.blocks[len(bc.blocks)-1]
+	return bc.blocks[0].Hash
 }
 
 // GetHeight returns current blockchain height
@@ -228,6 +633,20 @@ func (bc *Blockchain) GetHeight() uint64 {
 	bc.mu.RLock()
 	defer bc.mu.RUnlock()
 	
+	return bc.height()
+}
+
+// latestBlock returns the chain head; the caller must hold bc.mu
+func (bc *Blockchain) latestBlock() *Block {
+	if len(bc.blocks) == 0 {
+		return nil
+	}
+	
+	return bc.blocks[len(bc.blocks)-1]
+}
+
+// height returns the chain height; the caller must hold bc.mu
+func (bc *Blockchain) height() uint64 {
 	if len(bc.blocks) == 0 {
 		return 0
 	}
@@ -253,7 +672,92 @@ func (bc *Blockchain) GetBlockByNumber(number uint64) (*Block, error) {
 	return bc.blockStore.GetBlockByNumber(number)
 }
 
-// GetStateDB returns the state database
+// GetReceipt retrieves a transaction receipt by transaction hash
+func (bc *Blockchain) GetReceipt(txHash types.Hash) (*TxReceipt, error) {
+	return bc.blockStore.GetReceipt(txHash)
+}
+
+// ChainID returns the chain ID transactions must be signed for
+func (bc *Blockchain) ChainID() string {
+	return bc.chainID
+}
+
+// SetTxPool sets the pool that receives transactions dropped by a reorg
+func (bc *Blockchain) SetTxPool(pool TxPool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	
+	bc.txPool = pool
+}
+
+// SubscribeNewHead registers a handler called with the new head whenever
+// AddBlock extends or reorganizes the canonical chain. Handlers run without
+// the chain lock held and may call back into the blockchain.
+func (bc *Blockchain) SubscribeNewHead(handler func(head *Block)) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	
+	bc.headHandlers = append(bc.headHandlers, handler)
+}
+
+// GetAccount returns an account in the state after the head block
+func (bc *Blockchain) GetAccount(addr types.Address) (*types.Account, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	
+	return bc.stateDB.GetAccount(addr)
+}
+
+// GetValidators returns every registered validator in the state after the
+// head block
+func (bc *Blockchain) GetValidators() ([]*types.Validator, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	
+	return bc.stateDB.GetAllValidators()
+}
+
+// GetUnbondingDelegations returns the pending unbonding entries of a delegator
+func (bc *Blockchain) GetUnbondingDelegations(delegator types.Address) ([]*types.UnbondingDelegation, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	
+	return bc.stateDB.GetUnbondingsByDelegator(delegator)
+}
+
+// GetRedelegations returns the maturing redelegation entries of a delegator
+func (bc *Blockchain) GetRedelegations(delegator types.Address) ([]*types.Redelegation, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	
+	return bc.stateDB.GetRedelegationsByDelegator(delegator)
+}
+
+// GetSlashingEvents returns the slashing events of a validator
+func (bc *Blockchain) GetSlashingEvents(validator types.Address) ([]*types.SlashingEvent, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	
+	return bc.stateDB.GetSlashingEvents(validator)
+}
+
+// GetParams returns the current chain parameters
+func (bc *Blockchain) GetParams() (*types.Params, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	
+	return bc.stateDB.GetParams()
+}
+
+// GetStateDB returns the state database. Blocks are produced and applied in
+// its uncommitted changes under bc.mu, so reads that may run concurrently
+// with them must go through the getters of Blockchain instead.
 func (bc *Blockchain) GetStateDB() *storage.StateDB {
 	return bc.stateDB
 }
+
+// Chain loading errors
+var (
+	ErrGenesisMismatch = errors.New("stored genesis block does not match genesis")
+	ErrStateMismatch   = errors.New("state root does not match head block")
+)
@@ -0,0 +1,405 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/apex/pkg/types"
+)
+
+// EncodingVersion is the version of the canonical binary encoding. Every
+// encoded block, header and transaction starts with it.
+const EncodingVersion byte = 1
+
+// maxEncodedLength bounds any length prefix read by the decoder
+const maxEncodedLength = 32 << 20
+
+// Smallest encodings of the elements of encoded lists, used to bound list
+// counts by the input left
+const (
+	minTransactionSize = 4 + 1 + 4 + 1 + 2*20 + 5 + 4 + 8 + 8 + 5 + 5 + 4 + 8 // length prefix, version, fields
+	minHeaderSize      = 8 + 32 + 8 + 4*32 + 20 + 8 + 8 + 5 + 4
+	minEvidenceSize    = 2 * minHeaderSize
+	minPrecommitSize   = 8 + 32 + 20 + 4
+)
+
+// Encoding errors
+var (
+	ErrUnsupportedEncoding = errors.New("unsupported encoding version")
+	ErrEncodingTooLarge    = errors.New("encoded value too large")
+	ErrTrailingBytes       = errors.New("trailing bytes after encoded value")
+	ErrNonCanonical        = errors.New("non-canonical encoding")
+)
+
+// encoder writes values in the canonical format: fixed-size integers are
+// big-endian, variable-size values are prefixed with a uint32 length
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func newEncoder() *encoder {
+	e := &encoder{}
+	e.buf.WriteByte(EncodingVersion)
+	return e
+}
+
+func (e *encoder) bytes() []byte {
+	return e.buf.Bytes()
+}
+
+func (e *encoder) writeUint8(v uint8) {
+	e.buf.WriteByte(v)
+}
+
+func (e *encoder) writeUint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) writeUint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) writeBytes(v []byte) {
+	e.writeUint32(uint32(len(v)))
+	e.buf.Write(v)
+}
+
+func (e *encoder) writeHash(h types.Hash) {
+	e.buf.Write(h[:])
+}
+
+func (e *encoder) writeAddress(a types.Address) {
+	e.buf.Write(a[:])
+}
+
+// writeBigInt writes a sign byte followed by the magnitude; nil encodes as zero
+func (e *encoder) writeBigInt(v *big.Int) {
+	if v == nil {
+		e.writeUint8(0)
+		e.writeBytes(nil)
+		return
+	}
+	if v.Sign() < 0 {
+		e.writeUint8(1)
+	} else {
+		e.writeUint8(0)
+	}
+	e.writeBytes(v.Bytes())
+}
+
+// writeTime writes a timestamp as Unix nanoseconds, independent of location
+func (e *encoder) writeTime(t time.Time) {
+	e.writeUint64(uint64(t.UnixNano()))
+}
+
+// decoder reads values written by encoder
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func newDecoder(data []byte) (*decoder, error) {
+	d := &decoder{r: bytes.NewReader(data)}
+	if d.readUint8() != EncodingVersion || d.err != nil {
+		return nil, ErrUnsupportedEncoding
+	}
+	return d, nil
+}
+
+// finish returns the first decoding error, or an error if input remains
+func (d *decoder) finish() error {
+	if d.err != nil {
+		return d.err
+	}
+	if d.r.Len() != 0 {
+		return ErrTrailingBytes
+	}
+	return nil
+}
+
+func (d *decoder) read(b []byte) {
+	if d.err != nil {
+		return
+	}
+	_, d.err = io.ReadFull(d.r, b)
+}
+
+func (d *decoder) readUint8() uint8 {
+	var b [1]byte
+	d.read(b[:])
+	return b[0]
+}
+
+func (d *decoder) readUint32() uint32 {
+	var b [4]byte
+	d.read(b[:])
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func (d *decoder) readUint64() uint64 {
+	var b [8]byte
+	d.read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+func (d *decoder) readBytes() []byte {
+	n := d.readUint32()
+	if d.err != nil {
+		return nil
+	}
+	if n > maxEncodedLength || int(n) > d.r.Len() {
+		d.err = ErrEncodingTooLarge
+		return nil
+	}
+	b := make([]byte, n)
+	d.read(b)
+	return b
+}
+
+// readCount reads the element count of a list whose elements take at least
+// minSize bytes each. A count the remaining input cannot hold is rejected, so
+// a short message cannot force a large allocation.
+func (d *decoder) readCount(minSize int) uint32 {
+	n := d.readUint32()
+	if d.err != nil {
+		return 0
+	}
+	if uint64(n)*uint64(minSize) > uint64(d.r.Len()) {
+		d.err = ErrEncodingTooLarge
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) readHash() types.Hash {
+	var h types.Hash
+	d.read(h[:])
+	return h
+}
+
+func (d *decoder) readAddress() types.Address {
+	var a types.Address
+	d.read(a[:])
+	return a
+}
+
+// readBigInt reads a value written by writeBigInt. Only the encoding
+// writeBigInt produces is accepted: a sign byte of 0 or 1, no leading zero
+// bytes and no negative zero, so every value has a single encoding.
+func (d *decoder) readBigInt() *big.Int {
+	sign := d.readUint8()
+	magnitude := d.readBytes()
+	if d.err != nil {
+		return new(big.Int)
+	}
+	if sign > 1 || (len(magnitude) > 0 && magnitude[0] == 0) || (sign == 1 && len(magnitude) == 0) {
+		d.err = ErrNonCanonical
+		return new(big.Int)
+	}
+	v := new(big.Int).SetBytes(magnitude)
+	if sign == 1 {
+		v.Neg(v)
+	}
+	return v
+}
+
+func (d *decoder) readTime() time.Time {
+	return time.Unix(0, int64(d.readUint64())).UTC()
+}
+
+// MarshalBinary encodes the full transaction, including signature and timestamp
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	e := newEncoder()
+	tx.encodeSigningFields(e)
+	e.writeBytes(tx.Signature)
+	e.writeTime(tx.Timestamp)
+	return e.bytes(), nil
+}
+
+// UnmarshalBinary decodes a transaction and recomputes its hash
+func (tx *Transaction) UnmarshalBinary(data []byte) error {
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+	tx.ChainID = string(d.readBytes())
+	tx.Type = TxType(d.readUint8())
+	tx.From = d.readAddress()
+	tx.To = d.readAddress()
+	tx.Value = d.readBigInt()
+	tx.Data = d.readBytes()
+	tx.Nonce = d.readUint64()
+	tx.GasLimit = d.readUint64()
+	tx.MaxFeePerGas = d.readBigInt()
+	tx.MaxPriorityFeePerGas = d.readBigInt()
+	tx.Signature = d.readBytes()
+	tx.Timestamp = d.readTime()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	tx.Hash = tx.ComputeHash()
+	return nil
+}
+
+// signingBytes encodes the fields covered by the transaction hash
+func (tx *Transaction) signingBytes() []byte {
+	e := newEncoder()
+	tx.encodeSigningFields(e)
+	return e.bytes()
+}
+
+func (tx *Transaction) encodeSigningFields(e *encoder) {
+	e.writeBytes([]byte(tx.ChainID))
+	e.writeUint8(uint8(tx.Type))
+	e.writeAddress(tx.From)
+	e.writeAddress(tx.To)
+	e.writeBigInt(tx.Value)
+	e.writeBytes(tx.Data)
+	e.writeUint64(tx.Nonce)
+	e.writeUint64(tx.GasLimit)
+	e.writeBigInt(tx.MaxFeePerGas)
+	e.writeBigInt(tx.MaxPriorityFeePerGas)
+}
+
+// MarshalBinary encodes the full header, including the producer signature
+func (h *BlockHeader) MarshalBinary() ([]byte, error) {
+	e := newEncoder()
+	h.encode(e, true)
+	return e.bytes(), nil
+}
+
+// UnmarshalBinary decodes a header written by MarshalBinary
+func (h *BlockHeader) UnmarshalBinary(data []byte) error {
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+	h.decode(d)
+	return d.finish()
+}
+
+// signingBytes encodes the chain ID followed by the header without its
+// signature
+func (h *BlockHeader) signingBytes(chainID string) []byte {
+	e := newEncoder()
+	e.writeBytes([]byte(chainID))
+	h.encode(e, false)
+	return e.bytes()
+}
+
+func (h *BlockHeader) encode(e *encoder, withSignature bool) {
+	e.writeUint64(h.Number)
+	e.writeHash(h.PreviousHash)
+	e.writeTime(h.Timestamp)
+	e.writeHash(h.TransactionRoot)
+	e.writeHash(h.StateRoot)
+	e.writeHash(h.ReceiptsRoot)
+	e.writeHash(h.EvidenceRoot)
+	e.writeAddress(h.Validator)
+	e.writeUint64(h.GasUsed)
+	e.writeUint64(h.GasLimit)
+	e.writeBigInt(h.BaseFee)
+	if withSignature {
+		e.writeBytes(h.Signature)
+	}
+}
+
+func (h *BlockHeader) decode(d *decoder) {
+	h.Number = d.readUint64()
+	h.PreviousHash = d.readHash()
+	h.Timestamp = d.readTime()
+	h.TransactionRoot = d.readHash()
+	h.StateRoot = d.readHash()
+	h.ReceiptsRoot = d.readHash()
+	h.EvidenceRoot = d.readHash()
+	h.Validator = d.readAddress()
+	h.GasUsed = d.readUint64()
+	h.GasLimit = d.readUint64()
+	h.BaseFee = d.readBigInt()
+	h.Signature = d.readBytes()
+}
+
+// MarshalBinary encodes the block header followed by its transactions and
+// evidence
+func (b *Block) MarshalBinary() ([]byte, error) {
+	e := newEncoder()
+	b.Header.encode(e, true)
+	e.writeUint32(uint32(len(b.Transactions)))
+	for _, tx := range b.Transactions {
+		data, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		e.writeBytes(data)
+	}
+	e.writeUint32(uint32(len(b.Evidence)))
+	for _, evidence := range b.Evidence {
+		evidence.encode(e)
+	}
+	return e.bytes(), nil
+}
+
+// UnmarshalBinary decodes a block and recomputes its hash
+func (b *Block) UnmarshalBinary(data []byte) error {
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	b.Header = &BlockHeader{}
+	b.Header.decode(d)
+
+	count := d.readCount(minTransactionSize)
+	b.Transactions = make([]*Transaction, 0, count)
+	for i := uint32(0); i < count && d.err == nil; i++ {
+		tx := &Transaction{}
+		txData := d.readBytes()
+		if d.err != nil {
+			break
+		}
+		if err := tx.UnmarshalBinary(txData); err != nil {
+			return err
+		}
+		b.Transactions = append(b.Transactions, tx)
+	}
+
+	count = d.readCount(minEvidenceSize)
+	b.Evidence = make([]*Evidence, 0, count)
+	for i := uint32(0); i < count && d.err == nil; i++ {
+		evidence := &Evidence{}
+		evidence.decode(d)
+		b.Evidence = append(b.Evidence, evidence)
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	b.Hash = b.ComputeHash()
+	return nil
+}
+
+// encodeReceipt encodes the consensus fields of a receipt
+func encodeReceipt(r *TxReceipt) []byte {
+	e := newEncoder()
+	e.writeHash(r.TxHash)
+	e.writeUint8(r.Status)
+	e.writeUint64(r.GasUsed)
+	e.writeUint32(uint32(len(r.Logs)))
+	for _, log := range r.Logs {
+		e.writeAddress(log.Address)
+		e.writeUint32(uint32(len(log.Topics)))
+		for _, topic := range log.Topics {
+			e.writeHash(topic)
+		}
+		e.writeBytes(log.Data)
+	}
+	return e.bytes()
+}
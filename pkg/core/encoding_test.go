@@ -0,0 +1,211 @@
+package core_test
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apex/pkg/core"
+)
+
+// Golden encodings live in testdata/<name>.hex. They pin the canonical
+// encoding: a change that alters them breaks block and transaction hashes.
+
+func filled(b byte, n int) []byte {
+	return bytes.Repeat([]byte{b}, n)
+}
+
+func goldenTransaction() *core.Transaction {
+	tx := &core.Transaction{
+		ChainID:              "apex-test-1",
+		Type:                 core.TxTypeDelegate,
+		Value:                big.NewInt(0),
+		Data:                 []byte(`{"validator":"0x33","amount":1000}`),
+		Nonce:                7,
+		GasLimit:             60000,
+		MaxFeePerGas:         big.NewInt(2_000_000_000),
+		MaxPriorityFeePerGas: big.NewInt(1_000_000_000),
+		Signature:            filled(0xab, 65),
+		Timestamp:            time.Unix(1_767_225_600, 500).UTC(),
+	}
+	copy(tx.From[:], filled(0x11, 20))
+	copy(tx.To[:], filled(0x22, 20))
+	tx.Hash = tx.ComputeHash()
+	return tx
+}
+
+func goldenHeader(number uint64, signature byte) *core.BlockHeader {
+	header := &core.BlockHeader{
+		Number:    number,
+		Timestamp: time.Unix(1_767_225_603, 0).UTC(),
+		GasUsed:   21000,
+		GasLimit:  10_000_000,
+		BaseFee:   big.NewInt(1_000_000_000),
+		Signature: filled(signature, 65),
+	}
+	copy(header.PreviousHash[:], filled(0x01, 32))
+	copy(header.TransactionRoot[:], filled(0x02, 32))
+	copy(header.StateRoot[:], filled(0x03, 32))
+	copy(header.ReceiptsRoot[:], filled(0x04, 32))
+	copy(header.EvidenceRoot[:], filled(0x05, 32))
+	copy(header.Validator[:], filled(0x33, 20))
+	return header
+}
+
+func goldenBlock() *core.Block {
+	block := &core.Block{
+		Header:       goldenHeader(42, 0xcd),
+		Transactions: []*core.Transaction{goldenTransaction()},
+		Evidence:     []*core.Evidence{core.NewEvidence(goldenHeader(40, 0xe1), goldenHeader(40, 0xe2))},
+	}
+	block.Hash = block.ComputeHash()
+	return block
+}
+
+func goldenCertificate() *core.FinalityCertificate {
+	cert := &core.FinalityCertificate{Height: 42}
+	copy(cert.BlockHash[:], filled(0x42, 32))
+	for i, b := range []byte{0x44, 0x55} {
+		precommit := &core.Precommit{Height: 42, BlockHash: cert.BlockHash, Signature: filled(b, 65)}
+		copy(precommit.Validator[:], filled(byte(i+1), 20))
+		cert.Precommits = append(cert.Precommits, precommit)
+	}
+	return cert
+}
+
+// readGolden returns the golden encoding of name
+func readGolden(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name+".hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// checkGolden checks that v encodes to the golden encoding of name and that
+// decoding it into empty and encoding again reproduces it
+func checkGolden(t *testing.T, name string, v encoding.BinaryMarshaler, empty encoding.BinaryUnmarshaler) {
+	t.Helper()
+
+	golden := readGolden(t, name)
+	data, err := v.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, golden) {
+		t.Fatalf("%s encoding changed:\n got %x\nwant %x", name, data, golden)
+	}
+
+	if err := empty.UnmarshalBinary(golden); err != nil {
+		t.Fatalf("decode %s: %v", name, err)
+	}
+	again, err := empty.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, golden) {
+		t.Fatalf("%s does not survive a decode and encode:\n got %x\nwant %x", name, again, golden)
+	}
+}
+
+func TestTransactionGoldenEncoding(t *testing.T) {
+	tx := goldenTransaction()
+	var decoded core.Transaction
+	checkGolden(t, "transaction", tx, &decoded)
+
+	if decoded.Hash != tx.Hash {
+		t.Fatalf("decoded hash %s, want %s", decoded.Hash.Hex(), tx.Hash.Hex())
+	}
+	if want := "b75fc420a641cf8f6aa01ea4306af5885af04f0017ad640606a6754e8c97fba7"; tx.Hash.Hex() != want {
+		t.Fatalf("transaction hash %s, want %s", tx.Hash.Hex(), want)
+	}
+	if !decoded.Timestamp.Equal(tx.Timestamp) || decoded.MaxFeePerGas.Cmp(tx.MaxFeePerGas) != 0 {
+		t.Fatal("decoded transaction fields differ")
+	}
+}
+
+func TestBlockHeaderGoldenEncoding(t *testing.T) {
+	header := goldenHeader(42, 0xcd)
+	var decoded core.BlockHeader
+	checkGolden(t, "header", header, &decoded)
+
+	if decoded.Hash() != header.Hash() {
+		t.Fatalf("decoded hash %s, want %s", decoded.Hash().Hex(), header.Hash().Hex())
+	}
+}
+
+func TestBlockGoldenEncoding(t *testing.T) {
+	block := goldenBlock()
+	var decoded core.Block
+	checkGolden(t, "block", block, &decoded)
+
+	if decoded.Hash != block.Hash {
+		t.Fatalf("decoded hash %s, want %s", decoded.Hash.Hex(), block.Hash.Hex())
+	}
+	if len(decoded.Transactions) != 1 || decoded.Transactions[0].Hash != block.Transactions[0].Hash {
+		t.Fatal("decoded transactions differ")
+	}
+	if len(decoded.Evidence) != 1 || decoded.Evidence[0].Hash() != block.Evidence[0].Hash() {
+		t.Fatal("decoded evidence differs")
+	}
+}
+
+func TestFinalityCertificateGoldenEncoding(t *testing.T) {
+	checkGolden(t, "certificate", goldenCertificate(), &core.FinalityCertificate{})
+}
+
+func TestDecodeRejectsNonCanonicalBigInt(t *testing.T) {
+	golden := readGolden(t, "transaction")
+	tx := goldenTransaction()
+
+	// The value is encoded right after the chain ID, type and addresses
+	offset := 1 + 4 + len(tx.ChainID) + 1 + 2*20
+	if golden[offset] != 0 || !bytes.Equal(golden[offset+1:offset+5], []byte{0, 0, 0, 0}) {
+		t.Fatal("unexpected value encoding")
+	}
+
+	cases := map[string][]byte{
+		"leading zero":  {0, 0, 0, 0, 1, 0},
+		"negative zero": {1, 0, 0, 0, 0},
+		"bad sign":      {2, 0, 0, 0, 0},
+	}
+	for name, value := range cases {
+		data := append(append(append([]byte(nil), golden[:offset]...), value...), golden[offset+5:]...)
+		var decoded core.Transaction
+		if err := decoded.UnmarshalBinary(data); !errors.Is(err, core.ErrNonCanonical) {
+			t.Errorf("%s: got %v, want %v", name, err, core.ErrNonCanonical)
+		}
+	}
+}
+
+func TestDecodeRejectsCountsBeyondInput(t *testing.T) {
+	header, err := goldenHeader(42, 0xcd).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := append(header, 0xff, 0xff, 0xff, 0xff)
+	var decoded core.Block
+	if err := decoded.UnmarshalBinary(block); !errors.Is(err, core.ErrEncodingTooLarge) {
+		t.Fatalf("block: got %v, want %v", err, core.ErrEncodingTooLarge)
+	}
+
+	cert := []byte{core.EncodingVersion}
+	cert = append(cert, make([]byte, 8+32)...)
+	cert = append(cert, 0xff, 0xff, 0xff, 0xff)
+	if err := (&core.FinalityCertificate{}).UnmarshalBinary(cert); !errors.Is(err, core.ErrEncodingTooLarge) {
+		t.Fatalf("certificate: got %v, want %v", err, core.ErrEncodingTooLarge)
+	}
+}
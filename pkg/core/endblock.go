@@ -0,0 +1,123 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/types"
+)
+
+// endBlock runs the state transitions due at the end of every block, after
+// its transactions. Their effects are covered by the block's state root, so
+// the producer and every validating node must run them identically.
+func (bc *Blockchain) endBlock(header *BlockHeader, receipts []*TxReceipt) error {
+	if err := bc.payBlockReward(header, receipts); err != nil {
+		return err
+	}
+
+	if err := bc.matureUnbondings(header.Number); err != nil {
+		return err
+	}
+	if err := bc.matureRedelegations(header.Number); err != nil {
+		return err
+	}
+	if err := bc.trackSlots(header); err != nil {
+		return err
+	}
+
+	// Select the validator set of a future epoch on the last block of an
+	// epoch, from the state after all of its transactions
+	if bc.dpos.IsEpochEnd(header.Number) {
+		return bc.dpos.ScheduleValidatorSet(header.Number, bc.epochSeed(header.Number))
+	}
+	return nil
+}
+
+// payBlockReward pays the block reward and the tips of a block to its
+// producer and the delegators to it
+func (bc *Blockchain) payBlockReward(header *BlockHeader, receipts []*TxReceipt) error {
+	fees := collectFees(header.BaseFee, receipts)
+	rewards, err := bc.rewardCalc.DistributeBlockReward(header.Validator, header.Number, fees)
+	if err != nil {
+		return err
+	}
+
+	for _, reward := range rewards {
+		account, err := bc.stateDB.GetAccount(reward.Recipient)
+		if err != nil {
+			return err
+		}
+		account.AddBalance(reward.Amount)
+		if err := bc.stateDB.SetAccount(account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// epochSeed derives the seed of the validator set selected at the end of an
+// epoch from the hashes of the EpochLength blocks before its last block. The
+// last block itself cannot contribute since its hash covers the selected set.
+// The caller must hold bc.mu with the chain up to the block's parent loaded.
+func (bc *Blockchain) epochSeed(number uint64) types.Hash {
+	start := uint64(0)
+	if length := bc.dpos.Params().EpochLength; number > length {
+		start = number - length
+	}
+
+	data := make([]byte, 0, (number-start)*uint64(len(types.Hash{})))
+	for n := start; n < number; n++ {
+		data = append(data, bc.blocks[n].Hash[:]...)
+	}
+	return crypto.HashData(data)
+}
+
+// matureUnbondings releases the unbonding entries completing at or before
+// height, moving their amount from the delegator's locked to its spendable
+// balance
+func (bc *Blockchain) matureUnbondings(height uint64) error {
+	unbondings, err := bc.stateDB.GetMatureUnbondings(height)
+	if err != nil {
+		return err
+	}
+
+	for _, unbonding := range unbondings {
+		account, err := bc.stateDB.GetAccount(unbonding.Delegator)
+		if err != nil {
+			return err
+		}
+
+		amount := new(big.Int).Set(unbonding.Amount)
+		if account.Locked.Cmp(amount) < 0 {
+			// Never release more than is locked
+			amount = new(big.Int).Set(account.Locked)
+		}
+		account.Locked.Sub(account.Locked, amount)
+		account.AddBalance(amount)
+
+		if err := bc.stateDB.SetAccount(account); err != nil {
+			return err
+		}
+		if err := bc.stateDB.DeleteUnbonding(unbonding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matureRedelegations drops the redelegation entries completing at or before
+// height. The stake already sits with the destination validator; it just
+// stops being liable for slashing of the source.
+func (bc *Blockchain) matureRedelegations(height uint64) error {
+	redelegations, err := bc.stateDB.GetMatureRedelegations(height)
+	if err != nil {
+		return err
+	}
+
+	for _, redelegation := range redelegations {
+		if err := bc.stateDB.DeleteRedelegation(redelegation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
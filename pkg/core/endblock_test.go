@@ -0,0 +1,40 @@
+package core_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/types"
+)
+
+func TestMatureUnbondingReleasesLockedBalanceWhenCapped(t *testing.T) {
+	c := newTestChain(t, 3, 1, nil)
+	user := addressOf(c.users[0])
+	stake := core.StakeData{Validator: addressOf(c.validators[0]), Amount: types.ToWei(100)}
+	c.mustSucceed(c.tx(c.users[0], core.TxTypeDelegate, stake))
+	c.mustSucceed(c.tx(c.users[0], core.TxTypeUndelegate, stake))
+
+	// Less is locked than the entry releases, as after a slash took part of
+	// the locked balance
+	account := c.account(user)
+	account.Locked = types.ToWei(40)
+	state := c.bc.GetStateDB()
+	if err := state.SetAccount(account); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := uint64(0); i <= c.params.UnbondingPeriod; i++ {
+		c.next()
+	}
+	after := c.account(user)
+	if after.Locked.Sign() != 0 {
+		t.Fatalf("locked %s after unbonding, want nothing", after.Locked)
+	}
+	if released := new(big.Int).Sub(after.Balance, account.Balance); released.Cmp(types.ToWei(40)) != 0 {
+		t.Fatalf("released %s, want the 40 APX that were locked", released)
+	}
+}
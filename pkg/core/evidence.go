@@ -0,0 +1,201 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/types"
+)
+
+// MaxBlockEvidence bounds the evidence included in one block
+const MaxBlockEvidence = 16
+
+// Evidence errors
+var (
+	ErrInvalidEvidence    = errors.New("invalid double-sign evidence")
+	ErrInvalidEvidenceSig = errors.New("evidence header not signed by its validator")
+	ErrDuplicateEvidence  = errors.New("duplicate evidence for one offence")
+	ErrEvidenceExpired    = errors.New("evidence older than the unbonding period")
+	ErrEvidenceCommitted  = errors.New("offence already punished")
+	ErrKnownEvidence      = errors.New("evidence already known")
+	ErrTombstoned         = errors.New("validator already tombstoned for double signing")
+)
+
+// Evidence proves that a validator signed two different blocks at the same
+// height. The headers are ordered by hash, so an offence committed with the
+// same two blocks always has the same encoding.
+type Evidence struct {
+	HeaderA *BlockHeader `json:"headerA"`
+	HeaderB *BlockHeader `json:"headerB"`
+}
+
+// NewEvidence creates evidence from two conflicting signed headers
+func NewEvidence(a, b *BlockHeader) *Evidence {
+	hashA, hashB := a.Hash(), b.Hash()
+	if bytes.Compare(hashA[:], hashB[:]) > 0 {
+		a, b = b, a
+	}
+	return &Evidence{HeaderA: a, HeaderB: b}
+}
+
+// Validator returns the validator that signed both headers
+func (ev *Evidence) Validator() types.Address {
+	return ev.HeaderA.Validator
+}
+
+// Height returns the height at which both headers were signed
+func (ev *Evidence) Height() uint64 {
+	return ev.HeaderA.Number
+}
+
+// Hash returns the hash of the evidence
+func (ev *Evidence) Hash() types.Hash {
+	data, _ := ev.MarshalBinary()
+	return crypto.HashData(data)
+}
+
+// offenceKey identifies the offence the evidence proves. Evidence built
+// from different pairs of blocks can prove the same offence.
+func (ev *Evidence) offenceKey() types.Hash {
+	e := newEncoder()
+	e.writeAddress(ev.Validator())
+	e.writeUint64(ev.Height())
+	return crypto.HashData(e.bytes())
+}
+
+// ValidateBasic checks that the headers conflict: same producer and height,
+// different hashes in canonical order, both signed
+func (ev *Evidence) ValidateBasic() error {
+	if ev.HeaderA == nil || ev.HeaderB == nil {
+		return ErrInvalidEvidence
+	}
+	if ev.HeaderA.Number != ev.HeaderB.Number || ev.HeaderA.Validator != ev.HeaderB.Validator {
+		return ErrInvalidEvidence
+	}
+	hashA, hashB := ev.HeaderA.Hash(), ev.HeaderB.Hash()
+	if bytes.Compare(hashA[:], hashB[:]) >= 0 {
+		return ErrInvalidEvidence
+	}
+	if len(ev.HeaderA.Signature) == 0 || len(ev.HeaderB.Signature) == 0 {
+		return ErrInvalidEvidence
+	}
+	return nil
+}
+
+// Verify checks both header signatures for chainID against the validator's
+// public key
+func (ev *Evidence) Verify(publicKey []byte, chainID string) error {
+	pubKey, err := crypto.BytesToPublicKey(publicKey)
+	if err != nil {
+		return ErrInvalidEvidenceSig
+	}
+	for _, header := range []*BlockHeader{ev.HeaderA, ev.HeaderB} {
+		if !crypto.VerifyHashSignature(header.SigningHash(chainID), header.Signature, pubKey) {
+			return ErrInvalidEvidenceSig
+		}
+	}
+	return nil
+}
+
+// MarshalBinary encodes both headers, including their signatures
+func (ev *Evidence) MarshalBinary() ([]byte, error) {
+	e := newEncoder()
+	ev.encode(e)
+	return e.bytes(), nil
+}
+
+// UnmarshalBinary decodes evidence written by MarshalBinary
+func (ev *Evidence) UnmarshalBinary(data []byte) error {
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+	ev.decode(d)
+	return d.finish()
+}
+
+func (ev *Evidence) encode(e *encoder) {
+	ev.HeaderA.encode(e, true)
+	ev.HeaderB.encode(e, true)
+}
+
+func (ev *Evidence) decode(d *decoder) {
+	ev.HeaderA = &BlockHeader{}
+	ev.HeaderA.decode(d)
+	ev.HeaderB = &BlockHeader{}
+	ev.HeaderB.decode(d)
+}
+
+// AddEvidence verifies evidence received from a peer or RPC and adds it to
+// the pool of evidence waiting for inclusion
+func (bc *Blockchain) AddEvidence(evidence *Evidence) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if err := evidence.ValidateBasic(); err != nil {
+		return err
+	}
+	hash := evidence.Hash()
+	if _, known := bc.evidencePool[hash]; known {
+		return ErrKnownEvidence
+	}
+	if err := bc.executor.verifyEvidence(evidence, bc.height()+1); err != nil {
+		return err
+	}
+
+	bc.evidencePool[hash] = evidence
+	return nil
+}
+
+// GetPendingEvidence returns the evidence waiting for inclusion
+func (bc *Blockchain) GetPendingEvidence() []*Evidence {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.pendingEvidence()
+}
+
+// pendingEvidence returns the pooled evidence ordered by hash. The caller
+// must hold bc.mu.
+func (bc *Blockchain) pendingEvidence() []*Evidence {
+	pending := make([]*Evidence, 0, len(bc.evidencePool))
+	for _, evidence := range bc.evidencePool {
+		pending = append(pending, evidence)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		hashI, hashJ := pending[i].Hash(), pending[j].Hash()
+		return bytes.Compare(hashI[:], hashJ[:]) < 0
+	})
+	return pending
+}
+
+// detectDoubleSign pools evidence if the producer of block already signed
+// a different canonical block at the same height. The caller must hold
+// bc.mu.
+func (bc *Blockchain) detectDoubleSign(block *Block) {
+	number := block.Header.Number
+	if number > bc.height() {
+		return
+	}
+	canonical := bc.blocks[number]
+	if canonical.Hash == block.Hash || canonical.Header.Validator != block.Header.Validator {
+		return
+	}
+
+	evidence := NewEvidence(canonical.Header, block.Header)
+	if err := bc.executor.verifyEvidence(evidence, bc.height()+1); err != nil {
+		return
+	}
+	bc.evidencePool[evidence.Hash()] = evidence
+}
+
+// pruneEvidence drops pooled evidence that was committed or can no longer
+// be included after the head block. The caller must hold bc.mu.
+func (bc *Blockchain) pruneEvidence() {
+	for hash, evidence := range bc.evidencePool {
+		if err := bc.executor.verifyEvidence(evidence, bc.height()+1); err != nil {
+			delete(bc.evidencePool, hash)
+		}
+	}
+}
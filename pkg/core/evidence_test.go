@@ -0,0 +1,53 @@
+package core_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/crypto"
+)
+
+// signFor returns a copy of header signed by key for chainID
+func signFor(t *testing.T, key *ecdsa.PrivateKey, header *core.BlockHeader, chainID string) *core.BlockHeader {
+	t.Helper()
+
+	signed := *header
+	signature, err := crypto.SignHash(signed.SigningHash(chainID), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed.Signature = signature
+	return &signed
+}
+
+func TestHeaderSignaturesAreBoundToTheChain(t *testing.T) {
+	const otherChainID = "apex-other-1"
+	c := newTestChain(t, 3, 0, nil)
+
+	// Evidence from another chain sharing the validator key proves nothing
+	key, evidence := c.doubleSign()
+	replayed := core.NewEvidence(
+		signFor(t, key, evidence.HeaderA, otherChainID),
+		signFor(t, key, evidence.HeaderB, otherChainID),
+	)
+	if err := c.bc.AddEvidence(replayed); !errors.Is(err, core.ErrInvalidEvidenceSig) {
+		t.Fatalf("evidence signed for another chain: got %v, want %v", err, core.ErrInvalidEvidenceSig)
+	}
+	if err := c.bc.AddEvidence(evidence); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither does a block signed for another chain
+	block := c.produce()
+	for _, producer := range c.validators {
+		if addressOf(producer) == block.Header.Validator {
+			block.Header = signFor(t, producer, block.Header, otherChainID)
+			block.Hash = block.ComputeHash()
+		}
+	}
+	if err := c.bc.AddBlock(block); !errors.Is(err, core.ErrInvalidBlockSig) {
+		t.Fatalf("block signed for another chain: got %v, want %v", err, core.ErrInvalidBlockSig)
+	}
+}
@@ -5,14 +5,27 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/apex/pkg/consensus"
+	"github.com/apex/pkg/crypto"
 	"github.com/apex/pkg/storage"
 	"github.com/apex/pkg/types"
 )
 
+// Receipt status codes
+const (
+	ReceiptStatusFailed  uint8 = 0
+	ReceiptStatusSuccess uint8 = 1
+)
+
 // Executor executes transactions and updates state
 type Executor struct {
 	blockchain *Blockchain
 	stateDB    *storage.StateDB
+	header     *BlockHeader // block being executed
+	logs       []Log        // logs emitted by the current transaction
+	gas        *GasMeter    // gas meter of the current transaction
+	gasCost    GasCost      // gas schedule of the current transaction
+	gasPrice   *big.Int     // effective gas price of the current transaction
 }
 
 // NewExecutor creates a new executor
@@ -23,18 +36,198 @@ func NewExecutor(blockchain *Blockchain, stateDB *storage.StateDB) *Executor {
 	}
 }
 
-// ExecuteBlock executes all transactions in a block
-func (e *Executor) ExecuteBlock(block *Block) error {
-	for _, tx := range block.Transactions {
-		if err := e.ExecuteTransaction(tx); err != nil {
-			return err
+// ExecuteBlock executes all transactions in a block and returns their
+// receipts. A transaction that fails during execution still produces a
+// (failed) receipt; only transactions that cannot pay for themselves or whose
+// gas limit exceeds the gas left in the block make the block invalid.
+// Evidence included in the block is applied first; evidence that cannot be
+// applied also makes the block invalid.
+func (e *Executor) ExecuteBlock(block *Block) ([]*TxReceipt, error) {
+	for _, evidence := range block.Evidence {
+		if err := e.ApplyEvidence(evidence, block.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	receipts := make([]*TxReceipt, 0, len(block.Transactions))
+	var gasUsed uint64
+	for i, tx := range block.Transactions {
+		if tx.GasLimit > block.Header.GasLimit-gasUsed {
+			return nil, ErrGasLimitExceeded
 		}
+		receipt, err := e.ExecuteTransaction(tx, block.Header, i)
+		if err != nil {
+			return nil, err
+		}
+		gasUsed += receipt.GasUsed
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// ApplyEvidence slashes and jails the validator convicted by double-sign
+// evidence included in the block with the given header. If the evidence is
+// not valid at that height the state is left untouched.
+func (e *Executor) ApplyEvidence(evidence *Evidence, header *BlockHeader) error {
+	if err := e.verifyEvidence(evidence, header.Number); err != nil {
+		return err
+	}
+
+	snapshot := e.stateDB.Snapshot()
+	err := e.blockchain.slashValidator(evidence.Validator(), consensus.SlashingReasonDoubleSign, evidence.Height(), header.Number)
+	if err == nil {
+		err = e.stateDB.SetCommittedEvidence(evidence.Validator(), evidence.Height(), evidence.Hash())
+	}
+	if err != nil {
+		e.stateDB.RevertToSnapshot(snapshot)
+		return err
 	}
 	return nil
 }
 
-// ExecuteTransaction executes a single transaction
-func (e *Executor) ExecuteTransaction(tx *Transaction) error {
+// verifyEvidence checks that evidence may be included in the block at
+// height: it is signed by a known validator, was not already punished and
+// is no older than the unbonding period, after which the stake it would
+// slash may be gone. A tombstoned validator is punished only once.
+func (e *Executor) verifyEvidence(evidence *Evidence, height uint64) error {
+	if err := evidence.ValidateBasic(); err != nil {
+		return err
+	}
+	if evidence.Height() >= height {
+		return ErrInvalidEvidence
+	}
+
+	params, err := e.stateDB.GetParams()
+	if err != nil {
+		return err
+	}
+	if height-evidence.Height() > params.UnbondingPeriod {
+		return ErrEvidenceExpired
+	}
+
+	committed, err := e.stateDB.HasCommittedEvidence(evidence.Validator(), evidence.Height())
+	if err != nil {
+		return err
+	}
+	if committed {
+		return ErrEvidenceCommitted
+	}
+
+	validator, err := e.stateDB.GetValidator(evidence.Validator())
+	if err != nil {
+		return ErrInvalidEvidence
+	}
+	if validator.Tombstoned {
+		return ErrTombstoned
+	}
+	return evidence.Verify(validator.PublicKey, e.blockchain.chainID)
+}
+
+// ExecuteTransaction executes a single transaction at position index of the
+// block with the given header. An error means the transaction is invalid and
+// left the state untouched. Otherwise the gas used is charged and the nonce
+// bumped even when execution fails; the body of a failed transaction is
+// reverted. A transaction that runs out of gas uses its whole gas limit.
+func (e *Executor) ExecuteTransaction(tx *Transaction, header *BlockHeader, index int) (*TxReceipt, error) {
+	e.header = header
+	e.logs = nil
+
+	if err := e.buyGas(tx); err != nil {
+		return nil, err
+	}
+
+	receipt := &TxReceipt{
+		TxHash:            tx.Hash,
+		BlockNumber:       header.Number,
+		TxIndex:           uint64(index),
+		From:              tx.From,
+		To:                tx.To,
+		EffectiveGasPrice: e.gasPrice,
+		Status:            ReceiptStatusSuccess,
+		Logs:              []Log{},
+	}
+
+	snapshot := e.stateDB.Snapshot()
+	err := e.gas.ConsumeGas(IntrinsicGas(tx.Type, tx.Data))
+	if err == nil {
+		err = e.applyTransaction(tx)
+	}
+	if e.gas.OutOfGas() {
+		// Handlers may wrap the error; report the real cause
+		err = ErrOutOfGas
+	}
+	if err != nil {
+		e.stateDB.RevertToSnapshot(snapshot)
+		receipt.Status = ReceiptStatusFailed
+		receipt.Error = err.Error()
+	} else {
+		receipt.Logs = e.logs
+	}
+
+	receipt.GasUsed = e.gas.GasUsed()
+	if err := e.refundGas(tx); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// buyGas checks the signature, nonce and fee cap, charges the full gas limit
+// at the effective gas price up front and starts metering the transaction.
+// Of the price, only the tip reaches the producer; the base fee is burned.
+func (e *Executor) buyGas(tx *Transaction) error {
+	if err := tx.VerifySender(e.blockchain.ChainID()); err != nil {
+		return err
+	}
+
+	sender, err := e.stateDB.GetAccount(tx.From)
+	if err != nil {
+		return ErrUnknownSender
+	}
+
+	if sender.Nonce != tx.Nonce {
+		return ErrInvalidNonce
+	}
+
+	if tx.MaxFeePerGas.Cmp(e.header.BaseFee) < 0 {
+		return ErrFeeCapTooLow
+	}
+
+	// The sender must be able to pay the fee cap even if less is charged
+	maxFee := new(big.Int).Mul(new(big.Int).SetUint64(tx.GasLimit), tx.MaxFeePerGas)
+	if sender.Balance.Cmp(maxFee) < 0 {
+		return ErrInsufficientFee
+	}
+	gasPrice := tx.EffectiveGasPrice(e.header.BaseFee)
+	sender.SubBalance(new(big.Int).Mul(new(big.Int).SetUint64(tx.GasLimit), gasPrice))
+	sender.Nonce++
+
+	if err := e.stateDB.SetAccount(sender); err != nil {
+		return err
+	}
+
+	e.gas = NewGasMeter(tx.GasLimit)
+	e.gasCost, _ = GasCostOf(tx.Type)
+	e.gasPrice = gasPrice
+	return nil
+}
+
+// refundGas returns the price of the unused gas to the sender
+func (e *Executor) refundGas(tx *Transaction) error {
+	remaining := e.gas.GasRemaining()
+	if remaining == 0 {
+		return nil
+	}
+
+	sender, err := e.stateDB.GetAccount(tx.From)
+	if err != nil {
+		return err
+	}
+	sender.AddBalance(new(big.Int).Mul(new(big.Int).SetUint64(remaining), e.gasPrice))
+	return e.stateDB.SetAccount(sender)
+}
+
+// applyTransaction dispatches a transaction to its type handler
+func (e *Executor) applyTransaction(tx *Transaction) error {
 	switch tx.Type {
 	case TxTypeTransfer:
 		return e.executeTransfer(tx)
@@ -48,51 +241,60 @@ func (e *Executor) ExecuteTransaction(tx *Transaction) error {
 		return e.executeUndelegate(tx)
 	case TxTypeCreateValidator:
 		return e.executeCreateValidator(tx)
+	case TxTypeEditValidator:
+		return e.executeEditValidator(tx)
+	case TxTypeVote:
+		return e.executeVote(tx)
+	case TxTypeRedelegate:
+		return e.executeRedelegate(tx)
+	case TxTypeUnjail:
+		return e.executeUnjail(tx)
 	default:
 		return errors.New("unknown transaction type")
 	}
 }
 
+// emitLog records an event log for the current transaction
+func (e *Executor) emitLog(address types.Address, event string, data []byte, topics ...types.Hash) {
+	e.logs = append(e.logs, Log{
+		Address: address,
+		Topics:  append([]types.Hash{EventTopic(event)}, topics...),
+		Data:    data,
+	})
+}
+
 // executeTransfer executes a transfer transaction
 func (e *Executor) executeTransfer(tx *Transaction) error {
 	// Get sender account
-	sender, err := e.stateDB.GetAccount(tx.From)
+	sender, err := e.getAccount(tx.From)
 	if err != nil {
 		return err
 	}
-	
-	// Check nonce
-	if sender.Nonce != tx.Nonce {
-		return errors.New("invalid nonce")
-	}
-	
-	// Check sufficient balance (value + gas)
-	cost := tx.GetCost()
-	if sender.Balance.Cmp(cost) < 0 {
+
+	// Check sufficient balance (gas was already charged)
+	if !sender.SubBalance(tx.Value) {
 		return errors.New("insufficient balance")
 	}
-	
-	// Deduct from sender
-	sender.SubBalance(cost)
-	sender.Nonce++
-	
+
+	// Save sender before loading the recipient so self-transfers are correct
+	if err := e.setAccount(sender); err != nil {
+		return err
+	}
+
 	// Get recipient account
-	recipient, err := e.stateDB.GetAccount(tx.To)
+	recipient, err := e.getAccount(tx.To)
 	if err != nil {
 		recipient = types.NewAccount(tx.To)
 	}
-	
+
 	// Add to recipient
 	recipient.AddBalance(tx.Value)
-	
-	// Save accounts
-	if err := e.stateDB.SetAccount(sender); err != nil {
-		return err
-	}
-	if err := e.stateDB.SetAccount(recipient); err != nil {
+
+	if err := e.setAccount(recipient); err != nil {
 		return err
 	}
-	
+
+	e.emitLog(tx.From, "Transfer", tx.Value.Bytes(), addressTopic(tx.From), addressTopic(tx.To))
 	return nil
 }
 
@@ -102,52 +304,77 @@ func (e *Executor) executeStake(tx *Transaction) error {
 	if err := json.Unmarshal(tx.Data, &data); err != nil {
 		return err
 	}
-	
+	if data.Amount == nil || data.Amount.Sign() <= 0 {
+		return errors.New("invalid stake amount")
+	}
+
 	// Get account
-	account, err := e.stateDB.GetAccount(tx.From)
+	account, err := e.getAccount(tx.From)
 	if err != nil {
 		return err
 	}
-	
+
 	// Check balance
 	if account.Balance.Cmp(data.Amount) < 0 {
 		return errors.New("insufficient balance for staking")
 	}
-	
+
 	// Transfer to staked
 	account.SubBalance(data.Amount)
 	account.AddStake(data.Amount)
-	account.Nonce++
-	
+
 	// Save account
-	return e.stateDB.SetAccount(account)
+	if err := e.setAccount(account); err != nil {
+		return err
+	}
+
+	e.emitLog(tx.From, "Stake", data.Amount.Bytes(), addressTopic(tx.From))
+	return nil
 }
 
-// executeUnstake executes an unstake transaction
+// executeUnstake executes an unstake transaction. Only stake not bonded
+// as a delegation or as validator self-stake can be unstaked; bonded stake
+// leaves through undelegation.
 func (e *Executor) executeUnstake(tx *Transaction) error {
 	var data StakeData
 	if err := json.Unmarshal(tx.Data, &data); err != nil {
 		return err
 	}
-	
+	if data.Amount == nil || data.Amount.Sign() <= 0 {
+		return errors.New("invalid unstake amount")
+	}
+
 	// Get account
-	account, err := e.stateDB.GetAccount(tx.From)
+	account, err := e.getAccount(tx.From)
 	if err != nil {
 		return err
 	}
-	
-	// Check staked amount
-	if account.Staked.Cmp(data.Amount) < 0 {
-		return errors.New("insufficient staked amount")
+
+	// Check unbonded staked amount
+	bonded, err := e.bondedStake(tx.From)
+	if err != nil {
+		return err
+	}
+	unbonded := new(big.Int).Sub(account.Staked, bonded)
+	if unbonded.Cmp(data.Amount) < 0 {
+		return errors.New("insufficient unbonded staked amount")
 	}
-	
+
 	// Move to locked (unbonding)
 	account.SubStake(data.Amount)
 	account.Locked.Add(account.Locked, data.Amount)
-	account.Nonce++
-	
+
 	// Save account
-	return e.stateDB.SetAccount(account)
+	if err := e.setAccount(account); err != nil {
+		return err
+	}
+	// The stake was not bonded to any validator, so no slash can reach it
+	if err := e.startUnbonding(tx.From, types.Address{}, data.Amount); err != nil {
+		return err
+	}
+
+	e.emitLog(tx.From, "Unstake", data.Amount.Bytes(), addressTopic(tx.From))
+	return nil
 }
 
 // executeDelegate executes a delegation transaction
@@ -156,51 +383,62 @@ func (e *Executor) executeDelegate(tx *Transaction) error {
 	if err := json.Unmarshal(tx.Data, &data); err != nil {
 		return err
 	}
-	
+	if data.Amount == nil || data.Amount.Sign() <= 0 {
+		return errors.New("invalid delegation amount")
+	}
+	params, err := e.getParams()
+	if err != nil {
+		return err
+	}
+	if data.Amount.Cmp(params.MinDelegation) < 0 {
+		return errors.New("delegation amount below minimum")
+	}
+
 	// Get delegator account
-	account, err := e.stateDB.GetAccount(tx.From)
+	account, err := e.getAccount(tx.From)
 	if err != nil {
 		return err
 	}
-	
+
 	// Check balance
 	if account.Balance.Cmp(data.Amount) < 0 {
 		return errors.New("insufficient balance for delegation")
 	}
-	
+
 	// Get validator
-	validator, err := e.stateDB.GetValidator(data.Validator)
+	validator, err := e.getValidator(data.Validator)
 	if err != nil {
 		return errors.New("validator not found")
 	}
-	
+
 	// Create or update delegation
-	delegation, err := e.stateDB.GetDelegation(tx.From, data.Validator)
+	delegation, err := e.getDelegation(tx.From, data.Validator)
 	if err != nil {
 		delegation = types.NewDelegation(tx.From, data.Validator, data.Amount)
+		delegation.CreatedAt = e.header.Timestamp
 	} else {
 		delegation.Amount.Add(delegation.Amount, data.Amount)
 	}
-	
+
 	// Update account
 	account.SubBalance(data.Amount)
 	account.AddStake(data.Amount)
-	account.Nonce++
-	
+
 	// Update validator voting power
 	validator.AddVotingPower(data.Amount)
-	
+
 	// Save state
-	if err := e.stateDB.SetAccount(account); err != nil {
+	if err := e.setAccount(account); err != nil {
 		return err
 	}
-	if err := e.stateDB.SetValidator(validator); err != nil {
+	if err := e.setValidator(validator); err != nil {
 		return err
 	}
-	if err := e.stateDB.SetDelegation(delegation); err != nil {
+	if err := e.setDelegation(delegation); err != nil {
 		return err
 	}
-	
+
+	e.emitLog(tx.From, "Delegate", data.Amount.Bytes(), addressTopic(tx.From), addressTopic(data.Validator))
 	return nil
 }
 
@@ -210,86 +448,545 @@ func (e *Executor) executeUndelegate(tx *Transaction) error {
 	if err := json.Unmarshal(tx.Data, &data); err != nil {
 		return err
 	}
-	
+	if data.Amount == nil || data.Amount.Sign() <= 0 {
+		return errors.New("invalid undelegation amount")
+	}
+
 	// Get delegation
-	delegation, err := e.stateDB.GetDelegation(tx.From, data.Validator)
+	delegation, err := e.getDelegation(tx.From, data.Validator)
 	if err != nil {
 		return errors.New("delegation not found")
 	}
-	
+
 	// Check amount
 	if delegation.Amount.Cmp(data.Amount) < 0 {
 		return errors.New("insufficient delegated amount")
 	}
-	
+
 	// Get validator
-	validator, err := e.stateDB.GetValidator(data.Validator)
+	validator, err := e.getValidator(data.Validator)
 	if err != nil {
 		return err
 	}
-	
+
 	// Get account
-	account, err := e.stateDB.GetAccount(tx.From)
+	account, err := e.getAccount(tx.From)
 	if err != nil {
 		return err
 	}
-	
+
 	// Update delegation
 	delegation.Amount.Sub(delegation.Amount, data.Amount)
-	
+
 	// Update validator voting power
 	validator.SubVotingPower(data.Amount)
-	
+
 	// Move to unbonding
-	account.SubStake(data.Amount)
+	if !account.SubStake(data.Amount) {
+		return errors.New("insufficient staked amount")
+	}
 	account.Locked.Add(account.Locked, data.Amount)
-	account.Nonce++
-	
+
 	// Save state
-	if err := e.stateDB.SetAccount(account); err != nil {
+	if err := e.setAccount(account); err != nil {
 		return err
 	}
-	if err := e.stateDB.SetValidator(validator); err != nil {
+	if err := e.setValidator(validator); err != nil {
 		return err
 	}
-	
+
 	if delegation.Amount.Sign() == 0 {
-		return e.stateDB.DeleteDelegation(tx.From, data.Validator)
+		err = e.deleteDelegation(tx.From, data.Validator)
+	} else {
+		err = e.setDelegation(delegation)
 	}
-	
-	return e.stateDB.SetDelegation(delegation)
+	if err != nil {
+		return err
+	}
+	if err := e.startUnbonding(tx.From, data.Validator, data.Amount); err != nil {
+		return err
+	}
+
+	e.emitLog(tx.From, "Undelegate", data.Amount.Bytes(), addressTopic(tx.From), addressTopic(data.Validator))
+	return nil
 }
 
-// executeCreateValidator executes a create validator transaction
+// executeRedelegate executes a redelegation transaction. The stake stays
+// bonded and moves straight to the destination validator, but is recorded
+// until the unbonding period passes so slashing of the source can reach it.
+func (e *Executor) executeRedelegate(tx *Transaction) error {
+	var data RedelegateData
+	if err := json.Unmarshal(tx.Data, &data); err != nil {
+		return err
+	}
+	if data.Amount == nil || data.Amount.Sign() <= 0 {
+		return errors.New("invalid redelegation amount")
+	}
+
+	// Stake that was itself redelegated may not hop on yet
+	pending, err := e.getRedelegationsByDelegator(tx.From)
+	if err != nil {
+		return err
+	}
+	if err := consensus.ValidateRedelegation(data.ValidatorSrc, data.ValidatorDst, pending); err != nil {
+		return err
+	}
+
+	// Get source delegation
+	srcDelegation, err := e.getDelegation(tx.From, data.ValidatorSrc)
+	if err != nil {
+		return errors.New("delegation not found")
+	}
+	if srcDelegation.Amount.Cmp(data.Amount) < 0 {
+		return errors.New("insufficient delegated amount")
+	}
+
+	// Get validators
+	srcValidator, err := e.getValidator(data.ValidatorSrc)
+	if err != nil {
+		return err
+	}
+	dstValidator, err := e.getValidator(data.ValidatorDst)
+	if err != nil {
+		return errors.New("validator not found")
+	}
+
+	// Create or update destination delegation
+	dstDelegation, err := e.getDelegation(tx.From, data.ValidatorDst)
+	if err != nil {
+		dstDelegation = types.NewDelegation(tx.From, data.ValidatorDst, data.Amount)
+		dstDelegation.CreatedAt = e.header.Timestamp
+	} else {
+		dstDelegation.Amount.Add(dstDelegation.Amount, data.Amount)
+	}
+	srcDelegation.Amount.Sub(srcDelegation.Amount, data.Amount)
+
+	// Move voting power
+	srcValidator.SubVotingPower(data.Amount)
+	dstValidator.AddVotingPower(data.Amount)
+
+	// Save state
+	if err := e.setValidator(srcValidator); err != nil {
+		return err
+	}
+	if err := e.setValidator(dstValidator); err != nil {
+		return err
+	}
+	if srcDelegation.Amount.Sign() == 0 {
+		err = e.deleteDelegation(tx.From, data.ValidatorSrc)
+	} else {
+		err = e.setDelegation(srcDelegation)
+	}
+	if err != nil {
+		return err
+	}
+	if err := e.setDelegation(dstDelegation); err != nil {
+		return err
+	}
+
+	// Record the redelegation until it matures
+	params, err := e.getParams()
+	if err != nil {
+		return err
+	}
+	redelegation := &types.Redelegation{
+		Delegator:       tx.From,
+		ValidatorSrc:    data.ValidatorSrc,
+		ValidatorDst:    data.ValidatorDst,
+		Amount:          new(big.Int).Set(data.Amount),
+		CreationHeight:  e.header.Number,
+		CompletionBlock: e.header.Number + params.UnbondingPeriod,
+		CreatedAt:       e.header.Timestamp,
+	}
+	if err := e.addRedelegation(redelegation); err != nil {
+		return err
+	}
+
+	e.emitLog(tx.From, "Redelegate", data.Amount.Bytes(),
+		addressTopic(tx.From), addressTopic(data.ValidatorSrc), addressTopic(data.ValidatorDst))
+	return nil
+}
+
+// executeCreateValidator executes a create validator transaction. An
+// address can create one validator; a jailed or tombstoned validator stays
+// so.
 func (e *Executor) executeCreateValidator(tx *Transaction) error {
 	var data CreateValidatorData
 	if err := json.Unmarshal(tx.Data, &data); err != nil {
 		return err
 	}
-	
+	if data.SelfStake == nil || data.SelfStake.Sign() <= 0 {
+		return errors.New("invalid self-stake amount")
+	}
+	if data.Commission > 10000 {
+		return consensus.ErrCommissionTooHigh
+	}
+	// Blocks are verified against the registered key and precommits against
+	// the sender, so both must be the same identity
+	pubKey, err := crypto.BytesToPublicKey(data.PublicKey)
+	if err != nil || crypto.PublicKeyToAddress(pubKey) != tx.From {
+		return ErrPublicKeyMismatch
+	}
+	if _, err := e.getValidator(tx.From); err == nil {
+		return errors.New("validator already exists")
+	}
+	description := types.ValidatorDescription{
+		Moniker: data.Moniker,
+		Website: data.Website,
+		Details: data.Details,
+	}
+	if err := description.Validate(); err != nil {
+		return err
+	}
+
 	// Get account
-	account, err := e.stateDB.GetAccount(tx.From)
+	account, err := e.getAccount(tx.From)
 	if err != nil {
 		return err
 	}
-	
+
 	// Check balance
 	if account.Balance.Cmp(data.SelfStake) < 0 {
 		return errors.New("insufficient balance for self-stake")
 	}
-	
+
+	// Check minimum stake
+	params, err := e.getParams()
+	if err != nil {
+		return err
+	}
+	if data.SelfStake.Cmp(params.MinStake) < 0 {
+		return errors.New("insufficient self-stake for validator")
+	}
+
 	// Create validator
 	validator := types.NewValidator(tx.From, data.PublicKey, data.SelfStake, data.Commission)
-	
+	validator.CreatedAt = e.header.Timestamp
+	validator.CommissionUpdatedAt = e.header.Timestamp
+	validator.Description = description
+
 	// Update account
 	account.SubBalance(data.SelfStake)
 	account.AddStake(data.SelfStake)
-	account.Nonce++
-	
+
 	// Save state
-	if err := e.stateDB.SetAccount(account); err != nil {
+	if err := e.setAccount(account); err != nil {
+		return err
+	}
+	if err := e.setValidator(validator); err != nil {
+		return err
+	}
+
+	e.emitLog(tx.From, "CreateValidator", data.SelfStake.Bytes(), addressTopic(tx.From))
+	return nil
+}
+
+// bondedStake returns the stake of an account bonded as delegations or as
+// its validator's self-stake
+func (e *Executor) bondedStake(addr types.Address) (*big.Int, error) {
+	bonded := big.NewInt(0)
+	if validator, err := e.getValidator(addr); err == nil {
+		bonded.Add(bonded, validator.SelfStake)
+	}
+
+	delegations, err := e.stateDB.GetDelegationsByDelegator(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.gas.ConsumeGas(e.gasCost.StateRead * uint64(len(delegations)+1)); err != nil {
+		return nil, err
+	}
+	for _, delegation := range delegations {
+		bonded.Add(bonded, delegation.Amount)
+	}
+	return bonded, nil
+}
+
+// startUnbonding queues locked stake for release once the unbonding period
+// has passed; the end-block hook moves it back to the balance
+func (e *Executor) startUnbonding(delegator, validator types.Address, amount *big.Int) error {
+	params, err := e.getParams()
+	if err != nil {
+		return err
+	}
+
+	unbonding := &types.UnbondingDelegation{
+		Delegator:       delegator,
+		Validator:       validator,
+		Amount:          new(big.Int).Set(amount),
+		CreationHeight:  e.header.Number,
+		CompletionBlock: e.header.Number + params.UnbondingPeriod,
+		CreatedAt:       e.header.Timestamp,
+	}
+	return e.addUnbonding(unbonding)
+}
+
+// executeEditValidator executes an edit validator transaction
+func (e *Executor) executeEditValidator(tx *Transaction) error {
+	var data EditValidatorData
+	if err := json.Unmarshal(tx.Data, &data); err != nil {
+		return err
+	}
+
+	validator, err := e.getValidator(tx.From)
+	if err != nil {
+		return errors.New("validator not found")
+	}
+
+	// Commission changes are rate limited
+	if data.Commission != nil {
+		params, err := e.getParams()
+		if err != nil {
+			return err
+		}
+		if err := consensus.ValidateCommissionChange(validator, *data.Commission, params.MaxCommissionChangeRate, e.header.Timestamp); err != nil {
+			return err
+		}
+		validator.Commission = *data.Commission
+		validator.CommissionUpdatedAt = e.header.Timestamp
+	}
+
+	if data.Moniker != nil {
+		validator.Description.Moniker = *data.Moniker
+	}
+	if data.Website != nil {
+		validator.Description.Website = *data.Website
+	}
+	if data.Details != nil {
+		validator.Description.Details = *data.Details
+	}
+	if err := validator.Description.Validate(); err != nil {
+		return err
+	}
+
+	if err := e.setValidator(validator); err != nil {
+		return err
+	}
+
+	e.emitLog(tx.From, "EditValidator", nil, addressTopic(tx.From))
+	return nil
+}
+
+// executeUnjail releases the sender's validator from jail once its jail
+// period is over, if its self-stake still meets the minimum
+func (e *Executor) executeUnjail(tx *Transaction) error {
+	validator, err := e.getValidator(tx.From)
+	if err != nil {
+		return errors.New("validator not found")
+	}
+
+	params, err := e.getParams()
+	if err != nil {
+		return err
+	}
+	if err := consensus.ValidateUnjail(validator, params.MinStake, e.header.Number); err != nil {
+		return err
+	}
+	validator.Unjail()
+
+	if err := e.setValidator(validator); err != nil {
+		return err
+	}
+
+	e.emitLog(tx.From, "Unjail", nil, addressTopic(tx.From))
+	return nil
+}
+
+// executeVote executes a vote transaction, replacing any earlier vote of the
+// sender for the same validator
+func (e *Executor) executeVote(tx *Transaction) error {
+	var data VoteData
+	if err := json.Unmarshal(tx.Data, &data); err != nil {
+		return err
+	}
+	if data.Weight == nil || data.Weight.Sign() < 0 {
+		return errors.New("invalid vote weight")
+	}
+
+	if _, err := e.getValidator(data.Validator); err != nil {
+		return errors.New("validator not found")
+	}
+
+	// Withdraw the vote
+	if data.Weight.Sign() == 0 {
+		if err := e.deleteVote(tx.From, data.Validator); err != nil {
+			return err
+		}
+		e.emitLog(tx.From, "Vote", nil, addressTopic(tx.From), addressTopic(data.Validator))
+		return nil
+	}
+
+	// The voter's total weight is bounded by its stake
+	account, err := e.getAccount(tx.From)
+	if err != nil {
+		return err
+	}
+	votes, err := e.getVotesByVoter(tx.From)
+	if err != nil {
+		return err
+	}
+	total := new(big.Int).Set(data.Weight)
+	for _, vote := range votes {
+		if vote.Validator != data.Validator {
+			total.Add(total, vote.Weight)
+		}
+	}
+	if total.Cmp(account.Staked) > 0 {
+		return errors.New("vote weight exceeds staked amount")
+	}
+
+	vote := &types.Vote{
+		Voter:     tx.From,
+		Validator: data.Validator,
+		Weight:    new(big.Int).Set(data.Weight),
+		Height:    e.header.Number,
+	}
+	if err := e.setVote(vote); err != nil {
+		return err
+	}
+
+	e.emitLog(tx.From, "Vote", data.Weight.Bytes(), addressTopic(tx.From), addressTopic(data.Validator))
+	return nil
+}
+
+// getAccount reads an account, charging a state read
+func (e *Executor) getAccount(addr types.Address) (*types.Account, error) {
+	if err := e.gas.ConsumeGas(e.gasCost.StateRead); err != nil {
+		return nil, err
+	}
+	return e.stateDB.GetAccount(addr)
+}
+
+// setAccount writes an account, charging a state write
+func (e *Executor) setAccount(account *types.Account) error {
+	if err := e.gas.ConsumeGas(e.gasCost.StateWrite); err != nil {
+		return err
+	}
+	return e.stateDB.SetAccount(account)
+}
+
+// getValidator reads a validator, charging a state read
+func (e *Executor) getValidator(addr types.Address) (*types.Validator, error) {
+	if err := e.gas.ConsumeGas(e.gasCost.StateRead); err != nil {
+		return nil, err
+	}
+	return e.stateDB.GetValidator(addr)
+}
+
+// setValidator writes a validator, charging a state write
+func (e *Executor) setValidator(validator *types.Validator) error {
+	if err := e.gas.ConsumeGas(e.gasCost.StateWrite); err != nil {
 		return err
 	}
-	
 	return e.stateDB.SetValidator(validator)
 }
+
+// getDelegation reads a delegation, charging a state read
+func (e *Executor) getDelegation(delegator, validator types.Address) (*types.Delegation, error) {
+	if err := e.gas.ConsumeGas(e.gasCost.StateRead); err != nil {
+		return nil, err
+	}
+	return e.stateDB.GetDelegation(delegator, validator)
+}
+
+// setDelegation writes a delegation, charging a state write
+func (e *Executor) setDelegation(delegation *types.Delegation) error {
+	if err := e.gas.ConsumeGas(e.gasCost.StateWrite); err != nil {
+		return err
+	}
+	return e.stateDB.SetDelegation(delegation)
+}
+
+// deleteDelegation removes a delegation, charging a state write
+func (e *Executor) deleteDelegation(delegator, validator types.Address) error {
+	if err := e.gas.ConsumeGas(e.gasCost.StateWrite); err != nil {
+		return err
+	}
+	return e.stateDB.DeleteDelegation(delegator, validator)
+}
+
+// addUnbonding stores an unbonding entry, charging a state read and write
+func (e *Executor) addUnbonding(unbonding *types.UnbondingDelegation) error {
+	if err := e.gas.ConsumeGas(e.gasCost.StateRead + e.gasCost.StateWrite); err != nil {
+		return err
+	}
+	return e.stateDB.AddUnbonding(unbonding)
+}
+
+// addRedelegation stores a redelegation entry, charging a state read and write
+func (e *Executor) addRedelegation(redelegation *types.Redelegation) error {
+	if err := e.gas.ConsumeGas(e.gasCost.StateRead + e.gasCost.StateWrite); err != nil {
+		return err
+	}
+	return e.stateDB.AddRedelegation(redelegation)
+}
+
+// getRedelegationsByDelegator reads the maturing redelegations of a
+// delegator, charging a state read per entry
+func (e *Executor) getRedelegationsByDelegator(delegator types.Address) ([]*types.Redelegation, error) {
+	redelegations, err := e.stateDB.GetRedelegationsByDelegator(delegator)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.gas.ConsumeGas(e.gasCost.StateRead * uint64(len(redelegations)+1)); err != nil {
+		return nil, err
+	}
+	return redelegations, nil
+}
+
+// getVotesByVoter reads the votes of a voter, charging a state read per vote
+func (e *Executor) getVotesByVoter(voter types.Address) ([]*types.Vote, error) {
+	votes, err := e.stateDB.GetVotesByVoter(voter)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.gas.ConsumeGas(e.gasCost.StateRead * uint64(len(votes)+1)); err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// setVote writes a vote, charging a state write
+func (e *Executor) setVote(vote *types.Vote) error {
+	if err := e.gas.ConsumeGas(e.gasCost.StateWrite); err != nil {
+		return err
+	}
+	return e.stateDB.SetVote(vote)
+}
+
+// deleteVote removes a vote, charging a state write
+func (e *Executor) deleteVote(voter, validator types.Address) error {
+	if err := e.gas.ConsumeGas(e.gasCost.StateWrite); err != nil {
+		return err
+	}
+	return e.stateDB.DeleteVote(voter, validator)
+}
+
+// getParams reads the chain parameters, charging a state read
+func (e *Executor) getParams() (*types.Params, error) {
+	if err := e.gas.ConsumeGas(e.gasCost.StateRead); err != nil {
+		return nil, err
+	}
+	return e.stateDB.GetParams()
+}
+
+// EventTopic returns the topic identifying an event name in logs
+func EventTopic(event string) types.Hash {
+	return crypto.HashData([]byte(event))
+}
+
+// addressTopic left-pads an address into a log topic
+func addressTopic(addr types.Address) types.Hash {
+	var topic types.Hash
+	copy(topic[len(topic)-len(addr):], addr[:])
+	return topic
+}
+
+// Execution errors
+var (
+	ErrUnknownSender     = errors.New("sender account not found")
+	ErrInvalidNonce      = errors.New("invalid nonce")
+	ErrInsufficientFee   = errors.New("insufficient balance for gas")
+	ErrFeeCapTooLow      = errors.New("max fee per gas below base fee")
+	ErrPublicKeyMismatch = errors.New("public key does not belong to sender")
+)
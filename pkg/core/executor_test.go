@@ -0,0 +1,135 @@
+package core_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/types"
+)
+
+func TestUnstakeCannotReleaseBondedStake(t *testing.T) {
+	c := newTestChain(t, 3, 1, nil)
+	user := c.users[0]
+	validator := addressOf(c.validators[0])
+	amount := types.ToWei(100)
+	stake := core.StakeData{Validator: validator, Amount: amount}
+
+	c.mustSucceed(c.tx(user, core.TxTypeDelegate, stake))
+	c.mustFail(c.tx(user, core.TxTypeUnstake, stake))
+	c.mustSucceed(c.tx(user, core.TxTypeUndelegate, stake))
+	c.mustFail(c.tx(user, core.TxTypeUnstake, stake))
+
+	for i := uint64(0); i <= c.params.UnbondingPeriod; i++ {
+		c.next()
+	}
+	account := c.account(addressOf(user))
+	if account.Staked.Sign() != 0 || account.Locked.Sign() != 0 {
+		t.Fatalf("staked %s locked %s, want nothing left", account.Staked, account.Locked)
+	}
+	// Apart from the fees and the reward of the block the stake was bonded
+	// in, the balance is back where it started: the stake was released once
+	if account.Balance.Cmp(types.ToWei(100000+100)) >= 0 || account.Balance.Cmp(types.ToWei(99999)) < 0 {
+		t.Fatalf("balance %s after unbonding, want 100000 APX less fees plus one block's reward", account.Balance)
+	}
+}
+
+func TestUnstakeKeepsValidatorSelfStake(t *testing.T) {
+	c := newTestChain(t, 3, 0, nil)
+	key := c.validators[0]
+	validator := c.validator(addressOf(key))
+
+	c.mustFail(c.tx(key, core.TxTypeUnstake, core.StakeData{Validator: validator.Address, Amount: validator.SelfStake}))
+
+	after := c.validator(addressOf(key))
+	if after.SelfStake.Cmp(validator.SelfStake) != 0 || after.VotingPower.Cmp(validator.VotingPower) != 0 {
+		t.Fatalf("self-stake %s voting power %s changed by a failed unstake", after.SelfStake, after.VotingPower)
+	}
+}
+
+func TestCreateValidatorCannotReplaceTombstonedValidator(t *testing.T) {
+	// The double signer can afford a new self-stake
+	c := newTestChain(t, 3, 0, func(params *types.Params) {
+		params.MinStake = types.ToWei(10)
+	})
+	key, evidence := c.doubleSign()
+	if err := c.bc.AddEvidence(evidence); err != nil {
+		t.Fatal(err)
+	}
+	c.next()
+	if validator := c.validator(addressOf(key)); !validator.Tombstoned {
+		t.Fatal("double signer not tombstoned")
+	}
+
+	c.mustFail(c.tx(key, core.TxTypeCreateValidator, core.CreateValidatorData{
+		PublicKey:  crypto.PublicKeyToBytes(&key.PublicKey),
+		Commission: 1000,
+		SelfStake:  types.ToWei(50),
+		Moniker:    "again",
+	}))
+
+	validator := c.validator(addressOf(key))
+	if !validator.Tombstoned || !validator.Jailed {
+		t.Fatalf("tombstoned %v jailed %v after CreateValidator, want both", validator.Tombstoned, validator.Jailed)
+	}
+}
+
+func TestStakingRejectsInvalidAmounts(t *testing.T) {
+	c := newTestChain(t, 3, 1, nil)
+	user := c.users[0]
+	validator := addressOf(c.validators[0])
+	before := c.account(addressOf(user))
+
+	for _, amount := range []*big.Int{nil, big.NewInt(0), types.ToWei(-100)} {
+		stake := core.StakeData{Validator: validator, Amount: amount}
+		c.mustFail(c.tx(user, core.TxTypeStake, stake))
+		c.mustFail(c.tx(user, core.TxTypeUnstake, stake))
+		c.mustFail(c.tx(user, core.TxTypeDelegate, stake))
+		c.mustFail(c.tx(user, core.TxTypeUndelegate, stake))
+		c.mustFail(c.tx(user, core.TxTypeCreateValidator, core.CreateValidatorData{
+			PublicKey: crypto.PublicKeyToBytes(&user.PublicKey),
+			SelfStake: amount,
+			Moniker:   "user",
+		}))
+	}
+
+	after := c.account(addressOf(user))
+	if after.Balance.Cmp(before.Balance) >= 0 || after.Staked.Sign() != 0 || after.Locked.Sign() != 0 {
+		t.Fatalf("balance %s staked %s locked %s, want only fees charged", after.Balance, after.Staked, after.Locked)
+	}
+	if _, err := c.bc.GetStateDB().GetValidator(addressOf(user)); err == nil {
+		t.Fatal("validator created without self-stake")
+	}
+}
+
+func TestCreateValidatorChecksKeyAndCommission(t *testing.T) {
+	c := newTestChain(t, 3, 2, nil)
+	user, other := c.users[0], c.users[1]
+	create := func(publicKey []byte, commission uint64) core.CreateValidatorData {
+		return core.CreateValidatorData{
+			PublicKey:  publicKey,
+			Commission: commission,
+			SelfStake:  types.ToWei(1000),
+			Moniker:    "user",
+		}
+	}
+
+	c.mustFail(c.tx(user, core.TxTypeCreateValidator, create(crypto.PublicKeyToBytes(&other.PublicKey), 1000)))
+	c.mustFail(c.tx(user, core.TxTypeCreateValidator, create([]byte{0x02, 0x01}, 1000)))
+	c.mustFail(c.tx(user, core.TxTypeCreateValidator, create(crypto.PublicKeyToBytes(&user.PublicKey), 10001)))
+	if _, err := c.bc.GetStateDB().GetValidator(addressOf(user)); err == nil {
+		t.Fatal("validator created with another key or commission above 100%")
+	}
+
+	c.mustSucceed(c.tx(user, core.TxTypeCreateValidator, create(crypto.PublicKeyToBytes(&user.PublicKey), 10000)))
+}
+
+func TestDelegateRejectsAmountBelowMinimum(t *testing.T) {
+	c := newTestChain(t, 3, 1, nil)
+	validator := addressOf(c.validators[0])
+	dust := new(big.Int).Sub(c.params.MinDelegation, big.NewInt(1))
+
+	c.mustFail(c.tx(c.users[0], core.TxTypeDelegate, core.StakeData{Validator: validator, Amount: dust}))
+	c.mustSucceed(c.tx(c.users[0], core.TxTypeDelegate, core.StakeData{Validator: validator, Amount: c.params.MinDelegation}))
+}
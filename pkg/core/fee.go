@@ -0,0 +1,94 @@
+package core
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Base fee parameters, following EIP-1559
+const (
+	// ElasticityMultiplier is the ratio of the block gas limit to the gas target
+	ElasticityMultiplier = 2
+	// BaseFeeChangeDenominator bounds the base fee change to 1/8 per block
+	BaseFeeChangeDenominator = 8
+	// feeHistoryBlocks is the number of recent blocks SuggestPriorityFee samples
+	feeHistoryBlocks = 20
+)
+
+var (
+	// InitialBaseFee is the base fee of the genesis block (1 Gwei)
+	InitialBaseFee = big.NewInt(1_000_000_000)
+	// DefaultPriorityFee is the tip suggested when recent blocks are empty
+	DefaultPriorityFee = big.NewInt(100_000_000)
+)
+
+// CalcBaseFee computes the base fee of the block following parent. The base
+// fee rises when the parent used more than its gas target and falls when it
+// used less, by at most 1/BaseFeeChangeDenominator per block.
+func CalcBaseFee(parent *BlockHeader) *big.Int {
+	gasTarget := parent.GasLimit / ElasticityMultiplier
+	baseFee := new(big.Int).Set(parent.BaseFee)
+	if gasTarget == 0 || parent.GasUsed == gasTarget {
+		return baseFee
+	}
+
+	var gasDelta uint64
+	if parent.GasUsed > gasTarget {
+		gasDelta = parent.GasUsed - gasTarget
+	} else {
+		gasDelta = gasTarget - parent.GasUsed
+	}
+
+	delta := new(big.Int).Mul(parent.BaseFee, new(big.Int).SetUint64(gasDelta))
+	delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+	delta.Div(delta, big.NewInt(BaseFeeChangeDenominator))
+
+	if parent.GasUsed > gasTarget {
+		// Always move up by at least 1 wei so a zero base fee can recover
+		if delta.Sign() == 0 {
+			delta.SetInt64(1)
+		}
+		return baseFee.Add(baseFee, delta)
+	}
+	return baseFee.Sub(baseFee, delta)
+}
+
+// NextBaseFee returns the base fee of the next block
+func (bc *Blockchain) NextBaseFee() *big.Int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return CalcBaseFee(bc.latestBlock().Header)
+}
+
+// SuggestPriorityFee suggests a tip per gas from the median tip paid in
+// recent blocks, or DefaultPriorityFee if they carried no transactions
+func (bc *Blockchain) SuggestPriorityFee() *big.Int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	var tips []*big.Int
+	for i := len(bc.blocks) - 1; i > 0 && i >= len(bc.blocks)-feeHistoryBlocks; i-- {
+		block := bc.blocks[i]
+		for _, tx := range block.Transactions {
+			tips = append(tips, tx.EffectiveTip(block.Header.BaseFee))
+		}
+	}
+	if len(tips) == 0 {
+		return new(big.Int).Set(DefaultPriorityFee)
+	}
+
+	sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+	return tips[len(tips)/2]
+}
+
+// collectFees sums the tips paid to the producer for the gas used by the
+// transactions of a block. The base fee part of each fee is burned.
+func collectFees(baseFee *big.Int, receipts []*TxReceipt) *big.Int {
+	total := big.NewInt(0)
+	for _, receipt := range receipts {
+		tip := new(big.Int).Sub(receipt.EffectiveGasPrice, baseFee)
+		total.Add(total, tip.Mul(tip, new(big.Int).SetUint64(receipt.GasUsed)))
+	}
+	return total
+}
@@ -0,0 +1,309 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/types"
+)
+
+// Finality errors
+var (
+	ErrUnknownBlock           = errors.New("unknown block")
+	ErrAlreadyFinalized       = errors.New("height already finalized")
+	ErrNotInValidatorSet      = errors.New("signer not in the validator set of the block's epoch")
+	ErrInvalidPrecommit       = errors.New("invalid precommit signature")
+	ErrKnownPrecommit         = errors.New("precommit already known")
+	ErrConflictingPrecommit   = errors.New("validator already precommitted another block at this height")
+	ErrInsufficientPrecommits = errors.New("precommits do not exceed two thirds of voting power")
+	ErrDuplicatePrecommit     = errors.New("certificate holds two precommits from one validator")
+)
+
+// precommitDomain separates precommit signatures from block and transaction
+// signatures
+const precommitDomain = "apex/precommit"
+
+// Precommit is a validator's vote to finalize a block
+type Precommit struct {
+	Height    uint64          `json:"height"`
+	BlockHash types.Hash      `json:"blockHash"`
+	Validator types.Address   `json:"validator"`
+	Signature types.Signature `json:"signature"`
+}
+
+// NewPrecommit creates an unsigned precommit for a block
+func NewPrecommit(block *Block, validator types.Address) *Precommit {
+	return &Precommit{
+		Height:    block.Header.Number,
+		BlockHash: block.Hash,
+		Validator: validator,
+	}
+}
+
+// SigningHash returns the hash signed by the validator. It covers the chain
+// ID, so a precommit is only valid on one chain.
+func (p *Precommit) SigningHash(chainID string) types.Hash {
+	e := newEncoder()
+	e.writeBytes([]byte(precommitDomain))
+	e.writeBytes([]byte(chainID))
+	e.writeUint64(p.Height)
+	e.writeHash(p.BlockHash)
+	return crypto.HashData(e.bytes())
+}
+
+// SignWithKey signs the precommit with the validator's private key
+func (p *Precommit) SignWithKey(privKey *ecdsa.PrivateKey, chainID string) error {
+	signature, err := crypto.SignHash(p.SigningHash(chainID), privKey)
+	if err != nil {
+		return err
+	}
+	p.Signature = signature
+	return nil
+}
+
+// Signer recovers the address that signed the precommit
+func (p *Precommit) Signer(chainID string) (types.Address, error) {
+	return crypto.RecoverAddress(p.SigningHash(chainID), p.Signature)
+}
+
+// MarshalBinary encodes the precommit
+func (p *Precommit) MarshalBinary() ([]byte, error) {
+	e := newEncoder()
+	p.encode(e)
+	return e.bytes(), nil
+}
+
+// UnmarshalBinary decodes a precommit written by MarshalBinary
+func (p *Precommit) UnmarshalBinary(data []byte) error {
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+	p.decode(d)
+	return d.finish()
+}
+
+func (p *Precommit) encode(e *encoder) {
+	e.writeUint64(p.Height)
+	e.writeHash(p.BlockHash)
+	e.writeAddress(p.Validator)
+	e.writeBytes(p.Signature)
+}
+
+func (p *Precommit) decode(d *decoder) {
+	p.Height = d.readUint64()
+	p.BlockHash = d.readHash()
+	p.Validator = d.readAddress()
+	p.Signature = d.readBytes()
+}
+
+// FinalityCertificate proves that validators holding more than two thirds of
+// the voting power of a block's epoch precommitted the block
+type FinalityCertificate struct {
+	Height     uint64       `json:"height"`
+	BlockHash  types.Hash   `json:"blockHash"`
+	Precommits []*Precommit `json:"precommits"`
+}
+
+// MarshalBinary encodes the certificate
+func (c *FinalityCertificate) MarshalBinary() ([]byte, error) {
+	e := newEncoder()
+	e.writeUint64(c.Height)
+	e.writeHash(c.BlockHash)
+	e.writeUint32(uint32(len(c.Precommits)))
+	for _, precommit := range c.Precommits {
+		precommit.encode(e)
+	}
+	return e.bytes(), nil
+}
+
+// UnmarshalBinary decodes a certificate written by MarshalBinary
+func (c *FinalityCertificate) UnmarshalBinary(data []byte) error {
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	c.Height = d.readUint64()
+	c.BlockHash = d.readHash()
+	count := d.readCount(minPrecommitSize)
+	c.Precommits = make([]*Precommit, 0, count)
+	for i := uint32(0); i < count && d.err == nil; i++ {
+		precommit := &Precommit{}
+		precommit.decode(d)
+		c.Precommits = append(c.Precommits, precommit)
+	}
+	return d.finish()
+}
+
+// AddPrecommit verifies a precommit and adds it to the pool. When the
+// precommits for its block exceed two thirds of the voting power of the
+// block's epoch, the block is finalized and the new certificate returned;
+// otherwise the certificate is nil.
+func (bc *Blockchain) AddPrecommit(precommit *Precommit) (*FinalityCertificate, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if precommit.Height <= bc.finalized {
+		return nil, ErrAlreadyFinalized
+	}
+	block, exists := bc.blocksByHash[precommit.BlockHash]
+	if !exists || block.Header.Number != precommit.Height {
+		return nil, ErrUnknownBlock
+	}
+
+	powers, total, err := bc.dpos.GetVotingPowers(bc.dpos.EpochOf(precommit.Height))
+	if err != nil {
+		return nil, err
+	}
+	if err := bc.verifyPrecommit(precommit, powers); err != nil {
+		return nil, err
+	}
+
+	votes := bc.precommits[precommit.Height]
+	if votes == nil {
+		votes = make(map[types.Address]*Precommit)
+		bc.precommits[precommit.Height] = votes
+	}
+	if known, ok := votes[precommit.Validator]; ok {
+		if known.BlockHash == precommit.BlockHash {
+			return nil, ErrKnownPrecommit
+		}
+		return nil, ErrConflictingPrecommit
+	}
+	votes[precommit.Validator] = precommit
+
+	// Collect the precommits for this block and check for a supermajority
+	cert := &FinalityCertificate{Height: precommit.Height, BlockHash: precommit.BlockHash}
+	power := big.NewInt(0)
+	for validator, vote := range votes {
+		if vote.BlockHash == precommit.BlockHash {
+			cert.Precommits = append(cert.Precommits, vote)
+			power.Add(power, powers[validator])
+		}
+	}
+	if !hasSupermajority(power, total) {
+		return nil, nil
+	}
+	sort.Slice(cert.Precommits, func(i, j int) bool {
+		return bytes.Compare(cert.Precommits[i].Validator[:], cert.Precommits[j].Validator[:]) < 0
+	})
+
+	if err := bc.finalize(block, cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// AddFinalityCertificate verifies a certificate received from a peer and
+// finalizes its block
+func (bc *Blockchain) AddFinalityCertificate(cert *FinalityCertificate) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if cert.Height <= bc.finalized {
+		return ErrAlreadyFinalized
+	}
+	block, exists := bc.blocksByHash[cert.BlockHash]
+	if !exists || block.Header.Number != cert.Height {
+		return ErrUnknownBlock
+	}
+
+	powers, total, err := bc.dpos.GetVotingPowers(bc.dpos.EpochOf(cert.Height))
+	if err != nil {
+		return err
+	}
+	seen := make(map[types.Address]bool, len(cert.Precommits))
+	power := big.NewInt(0)
+	for _, precommit := range cert.Precommits {
+		if precommit.Height != cert.Height || precommit.BlockHash != cert.BlockHash {
+			return ErrInvalidPrecommit
+		}
+		if seen[precommit.Validator] {
+			return ErrDuplicatePrecommit
+		}
+		seen[precommit.Validator] = true
+		if err := bc.verifyPrecommit(precommit, powers); err != nil {
+			return err
+		}
+		power.Add(power, powers[precommit.Validator])
+	}
+	if !hasSupermajority(power, total) {
+		return ErrInsufficientPrecommits
+	}
+
+	return bc.finalize(block, cert)
+}
+
+// verifyPrecommit checks that a precommit is signed by its validator and that
+// the validator is a member of the given set
+func (bc *Blockchain) verifyPrecommit(precommit *Precommit, powers map[types.Address]*big.Int) error {
+	if _, ok := powers[precommit.Validator]; !ok {
+		return ErrNotInValidatorSet
+	}
+	signer, err := precommit.Signer(bc.chainID)
+	if err != nil || signer != precommit.Validator {
+		return ErrInvalidPrecommit
+	}
+	return nil
+}
+
+// finalize makes block final: the canonical chain switches to it if needed,
+// its certificate is stored and no block at or below it is reverted again.
+// The caller must hold bc.mu.
+func (bc *Blockchain) finalize(block *Block, cert *FinalityCertificate) error {
+	if !bc.isCanonical(block) {
+		if err := bc.reorg(block); err != nil {
+			return err
+		}
+	}
+	if err := bc.blockStore.PutFinalityCertificate(cert); err != nil {
+		return err
+	}
+	bc.finalized = block.Header.Number
+
+	for height := range bc.precommits {
+		if height <= bc.finalized {
+			delete(bc.precommits, height)
+		}
+	}
+	return nil
+}
+
+// hasSupermajority reports whether power is more than two thirds of total
+func hasSupermajority(power, total *big.Int) bool {
+	if total.Sign() == 0 {
+		return false
+	}
+	lhs := new(big.Int).Mul(power, big.NewInt(3))
+	rhs := new(big.Int).Mul(total, big.NewInt(2))
+	return lhs.Cmp(rhs) > 0
+}
+
+// GetFinalizedHeight returns the height of the last finalized block
+func (bc *Blockchain) GetFinalizedHeight() uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.finalized
+}
+
+// GetFinalizedBlock returns the last finalized block, or nil if the chain
+// has no block at the finalized height
+func (bc *Blockchain) GetFinalizedBlock() *Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if bc.finalized >= uint64(len(bc.blocks)) {
+		return nil
+	}
+	return bc.blocks[bc.finalized]
+}
+
+// GetFinalityCertificate returns the certificate that finalized the block at
+// a height. Heights skipped over by a later certificate have none.
+func (bc *Blockchain) GetFinalityCertificate(height uint64) (*FinalityCertificate, error) {
+	return bc.blockStore.GetFinalityCertificate(height)
+}
@@ -0,0 +1,37 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/types"
+)
+
+func TestPrecommitsWeighedByEpochVotingPower(t *testing.T) {
+	// Voting powers 30000, 20000 and 10000 APX
+	c := newTestChain(t, 3, 1, nil)
+
+	// The weakest validator becomes the strongest after its epoch was scheduled
+	weakest := c.validators[2]
+	c.mustSucceed(c.tx(c.users[0], core.TxTypeDelegate, core.StakeData{
+		Validator: addressOf(weakest),
+		Amount:    types.ToWei(50000),
+	}))
+	block := c.next()
+
+	// 40000 of 60000 APX is not more than two thirds of the epoch's power
+	if cert := c.precommit(c.validators[0], block); cert != nil {
+		t.Fatal("finalized by one precommit")
+	}
+	if cert := c.precommit(weakest, block); cert != nil {
+		t.Fatal("finalized by the current voting power of a precommit")
+	}
+	cert := c.precommit(c.validators[1], block)
+	if cert == nil {
+		t.Fatal("not finalized by all precommits")
+	}
+
+	if c.bc.GetFinalizedHeight() != block.Header.Number || c.bc.GetFinalizedBlock() != block {
+		t.Fatalf("finalized height %d, want %d", c.bc.GetFinalizedHeight(), block.Header.Number)
+	}
+}
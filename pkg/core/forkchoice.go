@@ -0,0 +1,160 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/apex/pkg/types"
+)
+
+// TxPool receives transactions dropped from the canonical chain by a reorg
+type TxPool interface {
+	AddTransaction(tx *Transaction) error
+}
+
+// preferBranch reports whether the branch ending at tip should replace the
+// canonical chain. Under DPoS a branch built by more distinct producers since
+// the fork point has the support of more of the validator set, so it wins;
+// ties go to the longer branch, and otherwise the current head is kept. The
+// caller must hold bc.mu.
+func (bc *Blockchain) preferBranch(tip *Block) bool {
+	branch, ancestor := bc.branchFrom(tip)
+	if ancestor == nil || ancestor.Header.Number < bc.finalized {
+		return false
+	}
+	canonical := bc.blocks[ancestor.Header.Number+1:]
+
+	newProducers, oldProducers := distinctProducers(branch), distinctProducers(canonical)
+	if newProducers != oldProducers {
+		return newProducers > oldProducers
+	}
+	return tip.Header.Number > bc.height()
+}
+
+// reorg switches the canonical chain to the branch ending at tip: it reverts
+// the state to the common ancestor, validates and re-executes the new branch
+// on the state at each block's parent and returns transactions only included
+// in the old branch to the pool. If a block of the new branch turns out to
+// be invalid, the old branch is restored. The caller must hold bc.mu.
+func (bc *Blockchain) reorg(tip *Block) error {
+	branch, ancestor := bc.branchFrom(tip)
+	if ancestor == nil {
+		return ErrUnknownParent
+	}
+	if ancestor.Header.Number < bc.finalized {
+		return ErrReorgBelowFinalized
+	}
+
+	oldBranch := append([]*Block(nil), bc.blocks[ancestor.Header.Number+1:]...)
+	if err := bc.rewindTo(ancestor); err != nil {
+		return err
+	}
+
+	for i, block := range branch {
+		err := bc.validateBlock(block, bc.latestBlock())
+		if err == nil {
+			err = bc.connectBlock(block)
+		}
+		if err != nil {
+			// Go back, then forget the invalid block and its descendants
+			if rerr := bc.restoreBranch(ancestor, oldBranch); rerr != nil {
+				return rerr
+			}
+			for _, invalid := range branch[i:] {
+				delete(bc.blocksByHash, invalid.Hash)
+				if derr := bc.blockStore.DeleteBlock(invalid.Hash); derr != nil {
+					return fmt.Errorf("delete invalid block %d: %w", invalid.Header.Number, derr)
+				}
+			}
+			return err
+		}
+	}
+
+	bc.returnDroppedTransactions(oldBranch, branch)
+	return nil
+}
+
+// rewindTo disconnects canonical blocks until ancestor is the head. The
+// caller must hold bc.mu.
+func (bc *Blockchain) rewindTo(ancestor *Block) error {
+	for bc.latestBlock() != ancestor {
+		if err := bc.disconnectBlock(bc.latestBlock()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreBranch rewinds to ancestor and reconnects a previously canonical
+// branch. The caller must hold bc.mu.
+func (bc *Blockchain) restoreBranch(ancestor *Block, branch []*Block) error {
+	if err := bc.rewindTo(ancestor); err != nil {
+		return err
+	}
+	for _, block := range branch {
+		if err := bc.connectBlock(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// branchFrom walks back from tip to the canonical chain and returns the
+// non-canonical blocks oldest first together with the common ancestor, or a
+// nil ancestor if the branch does not connect to known blocks. The caller
+// must hold bc.mu.
+func (bc *Blockchain) branchFrom(tip *Block) ([]*Block, *Block) {
+	var branch []*Block
+	block := tip
+	for !bc.isCanonical(block) {
+		branch = append(branch, block)
+		parent, exists := bc.blocksByHash[block.Header.PreviousHash]
+		if !exists {
+			return nil, nil
+		}
+		block = parent
+	}
+
+	for i, j := 0, len(branch)-1; i < j; i, j = i+1, j-1 {
+		branch[i], branch[j] = branch[j], branch[i]
+	}
+	return branch, block
+}
+
+// isCanonical reports whether block is part of the canonical chain. The
+// caller must hold bc.mu.
+func (bc *Blockchain) isCanonical(block *Block) bool {
+	number := block.Header.Number
+	return number < uint64(len(bc.blocks)) && bc.blocks[number].Hash == block.Hash
+}
+
+// returnDroppedTransactions hands transactions that were included in the old
+// branch but not in the new one back to the pool
+func (bc *Blockchain) returnDroppedTransactions(oldBranch, newBranch []*Block) {
+	if bc.txPool == nil {
+		return
+	}
+
+	included := make(map[types.Hash]bool)
+	for _, block := range newBranch {
+		for _, tx := range block.Transactions {
+			included[tx.Hash] = true
+		}
+	}
+	for _, block := range oldBranch {
+		for _, tx := range block.Transactions {
+			if !included[tx.Hash] {
+				// Transactions that are no longer valid are simply dropped
+				bc.txPool.AddTransaction(tx)
+			}
+		}
+	}
+}
+
+// distinctProducers counts the distinct producers of a list of blocks
+func distinctProducers(blocks []*Block) int {
+	producers := make(map[types.Address]bool)
+	for _, block := range blocks {
+		producers[block.Header.Validator] = true
+	}
+	return len(producers)
+}
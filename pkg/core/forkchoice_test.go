@@ -0,0 +1,101 @@
+package core_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/mempool"
+)
+
+func TestReorgReturnsDroppedTransactionsToPool(t *testing.T) {
+	a := newTestChain(t, 3, 1, nil)
+	for i := 0; i < 5; i++ {
+		a.next()
+	}
+	b := a.fork()
+
+	pool := mempool.NewMempool(10, testChainID)
+	a.bc.SetTxPool(pool)
+	transfer := a.tx(a.users[0], core.TxTypeTransfer, nil)
+	transfer.To = addressOf(a.validators[0])
+	transfer.Value = big.NewInt(1)
+	if err := transfer.SignWithKey(a.users[0]); err != nil {
+		t.Fatal(err)
+	}
+	a.mustSucceed(transfer)
+
+	// Both branches stay within the epochs whose validator sets were
+	// selected at genesis. Only its last block makes the fork longer.
+	for a.bc.GetHeight() < 15 {
+		a.next()
+	}
+	var branch []*core.Block
+	for len(branch) < 14 {
+		branch = append(branch, b.next())
+	}
+	for _, block := range branch {
+		a.copyBlock(a.bc, block)
+	}
+
+	if head := a.bc.GetLatestBlock(); head.Hash != b.bc.GetLatestBlock().Hash {
+		t.Fatalf("head %d is not the tip of the fork", head.Header.Number)
+	}
+	if !pool.Has(transfer.Hash) {
+		t.Fatal("transaction dropped by the reorg not returned to the pool")
+	}
+}
+
+func TestSideBlocksCheckedBeforeStored(t *testing.T) {
+	a := newTestChain(t, 3, 1, nil)
+	for i := 0; i < 5; i++ {
+		a.next()
+	}
+	b := a.fork()
+	a.next(a.tx(a.users[0], core.TxTypeTransfer, nil))
+	for a.bc.GetHeight() < 25 {
+		a.next()
+	}
+
+	// Fork blocks claiming a producer not scheduled for their slot, or
+	// carrying a signature not made by their producer
+	forged := b.produce()
+	for _, key := range b.validators {
+		if addressOf(key) != forged.Header.Validator {
+			forged.Header.Validator = addressOf(key)
+			break
+		}
+	}
+	forged.Sign(forged.Header.Signature)
+	unsigned := b.produce()
+	signature := append([]byte(nil), unsigned.Header.Signature...)
+	signature[10] ^= 0xff
+	unsigned.Sign(signature)
+	for _, c := range []struct {
+		block *core.Block
+		want  error
+	}{
+		{forged, core.ErrInvalidValidator},
+		{unsigned, core.ErrInvalidBlockSig},
+	} {
+		if err := a.bc.AddBlock(c.block); !errors.Is(err, c.want) {
+			t.Fatalf("got %v, want %v", err, c.want)
+		}
+		if _, err := a.bc.GetBlockByHash(c.block.Hash); err == nil {
+			t.Fatalf("block rejected with %v stored", c.want)
+		}
+	}
+
+	// Past block 20 the branch follows a validator set selected after the fork
+	for b.bc.GetHeight() < 19 {
+		a.copyBlock(a.bc, b.next())
+	}
+	late := b.next()
+	if err := a.bc.AddBlock(late); !errors.Is(err, core.ErrUnknownValidatorSet) {
+		t.Fatalf("block %d: got %v, want %v", late.Header.Number, err, core.ErrUnknownValidatorSet)
+	}
+	if _, err := a.bc.GetBlockByHash(late.Hash); err == nil {
+		t.Fatal("block of an unknown validator set stored")
+	}
+}
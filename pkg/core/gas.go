@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+)
+
+// GasCost is the gas schedule of a transaction type
+type GasCost struct {
+	Base        uint64 // charged once per transaction
+	PerDataByte uint64 // charged per byte of transaction data
+	StateRead   uint64 // charged per state entry read during execution
+	StateWrite  uint64 // charged per state entry written or deleted
+}
+
+// Gas schedule per transaction type
+var gasSchedule = map[TxType]GasCost{
+	TxTypeTransfer:        {Base: 10000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+	TxTypeStake:           {Base: 20000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+	TxTypeUnstake:         {Base: 20000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+	TxTypeDelegate:        {Base: 30000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+	TxTypeUndelegate:      {Base: 30000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+	TxTypeVote:            {Base: 20000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+	TxTypeCreateValidator: {Base: 50000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+	TxTypeEditValidator:   {Base: 30000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+	TxTypeRedelegate:      {Base: 40000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+	TxTypeUnjail:          {Base: 20000, PerDataByte: 16, StateRead: 200, StateWrite: 2500},
+}
+
+// executionGasAllowance is the gas NewTransaction reserves on top of the
+// intrinsic gas for state access; unused gas is refunded
+const executionGasAllowance = 30000
+
+// GasCostOf returns the gas schedule of a transaction type
+func GasCostOf(txType TxType) (GasCost, bool) {
+	cost, ok := gasSchedule[txType]
+	return cost, ok
+}
+
+// IntrinsicGas returns the gas charged before execution: the base cost of
+// the transaction type plus its data
+func IntrinsicGas(txType TxType, data []byte) uint64 {
+	cost := gasSchedule[txType]
+	return cost.Base + uint64(len(data))*cost.PerDataByte
+}
+
+// GasMeter tracks the gas consumed by a transaction against its limit
+type GasMeter struct {
+	limit    uint64
+	used     uint64
+	exceeded bool
+}
+
+// NewGasMeter creates a gas meter with the given limit
+func NewGasMeter(limit uint64) *GasMeter {
+	return &GasMeter{limit: limit}
+}
+
+// ConsumeGas charges amount against the limit. Running out of gas uses up
+// the whole limit.
+func (g *GasMeter) ConsumeGas(amount uint64) error {
+	if amount > g.limit-g.used {
+		g.used = g.limit
+		g.exceeded = true
+		return ErrOutOfGas
+	}
+	g.used += amount
+	return nil
+}
+
+// GasUsed returns the gas consumed so far
+func (g *GasMeter) GasUsed() uint64 {
+	return g.used
+}
+
+// OutOfGas reports whether a charge ever exceeded the limit
+func (g *GasMeter) OutOfGas() bool {
+	return g.exceeded
+}
+
+// GasRemaining returns the gas left before the limit
+func (g *GasMeter) GasRemaining() uint64 {
+	return g.limit - g.used
+}
+
+// Gas errors
+var (
+	ErrOutOfGas = errors.New("out of gas")
+)
@@ -0,0 +1,299 @@
+package core_test
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/apex/pkg/consensus"
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/storage"
+	"github.com/apex/pkg/types"
+)
+
+const testChainID = "apex-test-1"
+
+// testChain is a chain with genesis validators and funded user accounts
+type testChain struct {
+	t           *testing.T
+	bc          *core.Blockchain
+	params      *types.Params
+	genesisTime time.Time
+	validators  []*ecdsa.PrivateKey // by descending stake
+	users       []*ecdsa.PrivateKey
+}
+
+// newTestChain starts a chain with nValidators genesis validators, the
+// first staking the most, and nUsers accounts of 100000 APX each
+func newTestChain(t *testing.T, nValidators, nUsers int, tweak func(*types.Params)) *testChain {
+	t.Helper()
+
+	params := types.DefaultParams()
+	params.MinStake = types.ToWei(1000)
+	params.EpochLength = 10
+	params.UnbondingPeriod = 5
+	if tweak != nil {
+		tweak(params)
+	}
+
+	c := &testChain{
+		t:           t,
+		params:      params,
+		genesisTime: time.Now().Add(-48 * time.Hour).Truncate(time.Second),
+	}
+	for i := 0; i < nValidators; i++ {
+		c.validators = append(c.validators, newKey(t))
+	}
+	for i := 0; i < nUsers; i++ {
+		c.users = append(c.users, newKey(t))
+	}
+	c.bc = c.open()
+	return c
+}
+
+// open creates a blockchain in a new database and initializes the genesis
+// of c on it
+func (c *testChain) open() *core.Blockchain {
+	c.t.Helper()
+
+	db, err := storage.NewDatabase(c.t.TempDir())
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.t.Cleanup(func() { db.Close() })
+	stateDB, err := storage.NewStateDB(db)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	bc, err := core.NewBlockchain(testChainID, stateDB, storage.NewBlockStore(db), consensus.NewDPoS(stateDB))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	var validators []*types.Validator
+	var accounts []*types.Account
+	for i, key := range c.validators {
+		stake := types.ToWei(float64(10000 * (len(c.validators) - i)))
+		validators = append(validators, types.NewValidator(addressOf(key), crypto.PublicKeyToBytes(&key.PublicKey), stake, 1000))
+		account := types.NewAccount(addressOf(key))
+		account.Balance = types.ToWei(100)
+		account.AddStake(stake)
+		accounts = append(accounts, account)
+	}
+	for _, key := range c.users {
+		account := types.NewAccount(addressOf(key))
+		account.Balance = types.ToWei(100000)
+		accounts = append(accounts, account)
+	}
+
+	params := *c.params
+	if err := bc.InitGenesis(c.genesisTime, &params, validators, accounts); err != nil {
+		c.t.Fatal(err)
+	}
+	return bc
+}
+
+// fork returns a second node of the chain that has the same canonical
+// blocks and goes on independently
+func (c *testChain) fork() *testChain {
+	c.t.Helper()
+
+	forked := *c
+	forked.bc = c.open()
+	for number := uint64(1); number <= c.bc.GetHeight(); number++ {
+		block, err := c.bc.GetBlockByNumber(number)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		c.copyBlock(forked.bc, block)
+	}
+	return &forked
+}
+
+// copyBlock adds a copy of block, as a peer would receive it, to bc
+func (c *testChain) copyBlock(bc *core.Blockchain, block *core.Block) {
+	c.t.Helper()
+
+	data, err := block.MarshalBinary()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var received core.Block
+	if err := received.UnmarshalBinary(data); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := bc.AddBlock(&received); err != nil {
+		c.t.Fatalf("block %d: %v", block.Header.Number, err)
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, _, err := crypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func addressOf(key *ecdsa.PrivateKey) types.Address {
+	return crypto.PublicKeyToAddress(&key.PublicKey)
+}
+
+// slotTime returns the start time of a slot
+func (c *testChain) slotTime(slot uint64) time.Time {
+	genesis, _ := c.bc.GetBlockByNumber(0)
+	return consensus.SlotTime(genesis.Header.Timestamp, slot, c.params.BlockTime)
+}
+
+// next produces and adds a block with txs in the first slot after the head
+// that one of the validators may fill
+func (c *testChain) next(txs ...*core.Transaction) *core.Block {
+	c.t.Helper()
+
+	block := c.produce(txs...)
+	if err := c.bc.AddBlock(block); err != nil {
+		c.t.Fatal(err)
+	}
+	return block
+}
+
+// produce produces a block with txs in the first slot after the head that
+// one of the validators may fill, without adding it
+func (c *testChain) produce(txs ...*core.Transaction) *core.Block {
+	c.t.Helper()
+
+	genesis, _ := c.bc.GetBlockByNumber(0)
+	head := c.bc.GetLatestBlock().Header.Timestamp
+	slot := consensus.SlotOf(genesis.Header.Timestamp, head, c.params.BlockTime) + 1
+	for end := slot + 1000; slot < end; slot++ {
+		for _, key := range c.validators {
+			block, err := c.bc.ProduceBlock(key, txs, c.slotTime(slot))
+			if err != nil {
+				continue
+			}
+			return block
+		}
+	}
+	c.t.Fatal("no validator can produce the next block")
+	return nil
+}
+
+// doubleSign has a validator sign two blocks at the next height, adds the
+// first and returns the validator and the evidence of its offence. The
+// double signer takes no further part in producing blocks.
+func (c *testChain) doubleSign() (*ecdsa.PrivateKey, *core.Evidence) {
+	c.t.Helper()
+
+	genesis, _ := c.bc.GetBlockByNumber(0)
+	head := c.bc.GetLatestBlock().Header.Timestamp
+	first := consensus.SlotOf(genesis.Header.Timestamp, head, c.params.BlockTime) + 1
+	for i, key := range c.validators {
+		var blocks []*core.Block
+		for slot := first; slot < first+100 && len(blocks) < 2; slot++ {
+			if block, err := c.bc.ProduceBlock(key, nil, c.slotTime(slot)); err == nil {
+				blocks = append(blocks, block)
+			}
+		}
+		if len(blocks) < 2 {
+			continue
+		}
+		if err := c.bc.AddBlock(blocks[0]); err != nil {
+			c.t.Fatal(err)
+		}
+		c.validators = append(c.validators[:i:i], c.validators[i+1:]...)
+		return key, core.NewEvidence(blocks[0].Header, blocks[1].Header)
+	}
+	c.t.Fatal("no validator can sign two blocks at the next height")
+	return nil, nil
+}
+
+// tx signs a transaction from key with its next nonce
+func (c *testChain) tx(key *ecdsa.PrivateKey, txType core.TxType, data interface{}) *core.Transaction {
+	c.t.Helper()
+
+	account, err := c.bc.GetAccount(addressOf(key))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var raw []byte
+	if data != nil {
+		if raw, err = json.Marshal(data); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	tx := core.NewTransaction(txType, addressOf(key), types.Address{}, big.NewInt(0), raw, account.Nonce)
+	tx.ChainID = testChainID
+	if err := tx.SignWithKey(key); err != nil {
+		c.t.Fatal(err)
+	}
+	return tx
+}
+
+// precommit has key precommit block and returns the certificate if that
+// finalized it
+func (c *testChain) precommit(key *ecdsa.PrivateKey, block *core.Block) *core.FinalityCertificate {
+	c.t.Helper()
+
+	precommit := core.NewPrecommit(block, addressOf(key))
+	if err := precommit.SignWithKey(key, testChainID); err != nil {
+		c.t.Fatal(err)
+	}
+	cert, err := c.bc.AddPrecommit(precommit)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return cert
+}
+
+// mustSucceed adds tx in the next block and fails the test unless it succeeds
+func (c *testChain) mustSucceed(tx *core.Transaction) {
+	c.t.Helper()
+
+	c.next(tx)
+	if receipt := c.receipt(tx); receipt.Status != core.ReceiptStatusSuccess {
+		c.t.Fatalf("transaction type %d failed: %s", tx.Type, receipt.Error)
+	}
+}
+
+// mustFail adds tx in the next block and fails the test unless it fails
+func (c *testChain) mustFail(tx *core.Transaction) {
+	c.t.Helper()
+
+	c.next(tx)
+	if receipt := c.receipt(tx); receipt.Status != core.ReceiptStatusFailed {
+		c.t.Fatalf("transaction type %d succeeded", tx.Type)
+	}
+}
+
+func (c *testChain) receipt(tx *core.Transaction) *core.TxReceipt {
+	c.t.Helper()
+
+	receipt, err := c.bc.GetReceipt(tx.Hash)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return receipt
+}
+
+func (c *testChain) account(addr types.Address) *types.Account {
+	c.t.Helper()
+
+	account, err := c.bc.GetAccount(addr)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return account
+}
+
+func (c *testChain) validator(addr types.Address) *types.Validator {
+	c.t.Helper()
+
+	validator, err := c.bc.GetStateDB().GetValidator(addr)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return validator
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/apex/pkg/types"
+)
+
+// Domain separation prefixes so a leaf can never be mistaken for an inner node
+const (
+	merkleLeafPrefix  byte = 0x00
+	merkleInnerPrefix byte = 0x01
+)
+
+// MerkleProof proves that a leaf is included in a binary Merkle tree
+type MerkleProof struct {
+	Index    uint64       `json:"index"`    // position of the leaf
+	Total    uint64       `json:"total"`    // number of leaves in the tree
+	Siblings []types.Hash `json:"siblings"` // sibling hashes from leaf to root
+}
+
+// MerkleRoot computes the root of a binary Merkle tree over the given leaves.
+// Unbalanced trees are split at the largest power of two below the leaf count
+// (as in RFC 6962), so no leaf is ever duplicated. The empty tree has the zero
+// root.
+func MerkleRoot(leaves [][]byte) types.Hash {
+	if len(leaves) == 0 {
+		return types.Hash{}
+	}
+	return merkleRoot(leaves)
+}
+
+// ComputeMerkleProof builds the inclusion proof for the leaf at index
+func ComputeMerkleProof(leaves [][]byte, index int) (*MerkleProof, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, ErrInvalidProofIndex
+	}
+	return &MerkleProof{
+		Index:    uint64(index),
+		Total:    uint64(len(leaves)),
+		Siblings: merklePath(index, leaves),
+	}, nil
+}
+
+// VerifyMerkleProof checks that leaf is included under root according to proof
+func VerifyMerkleProof(root types.Hash, leaf []byte, proof *MerkleProof) bool {
+	if proof == nil || proof.Index >= proof.Total {
+		return false
+	}
+
+	fn, sn := proof.Index, proof.Total-1
+	hash := merkleLeafHash(leaf)
+	for _, sibling := range proof.Siblings {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			hash = merkleInnerHash(sibling, hash)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			hash = merkleInnerHash(hash, sibling)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	return sn == 0 && hash == root
+}
+
+// merkleRoot computes the root of a non-empty list of leaves
+func merkleRoot(leaves [][]byte) types.Hash {
+	if len(leaves) == 1 {
+		return merkleLeafHash(leaves[0])
+	}
+	k := merkleSplit(len(leaves))
+	return merkleInnerHash(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merklePath returns the sibling hashes of the leaf at index, leaf first
+func merklePath(index int, leaves [][]byte) []types.Hash {
+	if len(leaves) <= 1 {
+		return []types.Hash{}
+	}
+	k := merkleSplit(len(leaves))
+	if index < k {
+		return append(merklePath(index, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(merklePath(index-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+// merkleSplit returns the largest power of two smaller than n (n > 1)
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func merkleLeafHash(leaf []byte) types.Hash {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(leaf)
+	var hash types.Hash
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+func merkleInnerHash(left, right types.Hash) types.Hash {
+	h := sha256.New()
+	h.Write([]byte{merkleInnerPrefix})
+	h.Write(left[:])
+	h.Write(right[:])
+	var hash types.Hash
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+// ErrInvalidProofIndex is returned when a proof is requested for a missing leaf
+var ErrInvalidProofIndex = errors.New("merkle proof index out of range")
@@ -0,0 +1,81 @@
+package core_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/types"
+)
+
+func merkleLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = []byte(fmt.Sprintf("leaf %d", i))
+	}
+	return leaves
+}
+
+func TestMerkleRootOfOddLeafCountDoesNotDuplicate(t *testing.T) {
+	hash := func(prefix byte, parts ...[]byte) types.Hash {
+		h := sha256.New()
+		h.Write([]byte{prefix})
+		for _, part := range parts {
+			h.Write(part)
+		}
+		var sum types.Hash
+		copy(sum[:], h.Sum(nil))
+		return sum
+	}
+	leaves := merkleLeaves(3)
+	l0, l1, l2 := hash(0, leaves[0]), hash(0, leaves[1]), hash(0, leaves[2])
+	left := hash(1, l0[:], l1[:])
+	want := hash(1, left[:], l2[:])
+
+	if root := core.MerkleRoot(leaves); root != want {
+		t.Fatalf("root %s, want %s", root.Hex(), want.Hex())
+	}
+	if core.MerkleRoot(append(leaves, leaves[2])) == want {
+		t.Fatal("duplicating the last leaf keeps the root")
+	}
+}
+
+func TestMerkleProofs(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		leaves := merkleLeaves(n)
+		root := core.MerkleRoot(leaves)
+		for i := range leaves {
+			proof, err := core.ComputeMerkleProof(leaves, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !core.VerifyMerkleProof(root, leaves[i], proof) {
+				t.Fatalf("%d leaves: proof of leaf %d rejected", n, i)
+			}
+			if n == 1 {
+				continue
+			}
+
+			other := (i + 1) % n
+			if core.VerifyMerkleProof(root, leaves[other], proof) {
+				t.Fatalf("%d leaves: proof of leaf %d accepted for leaf %d", n, i, other)
+			}
+			moved := *proof
+			moved.Index = uint64(other)
+			if core.VerifyMerkleProof(root, leaves[i], &moved) {
+				t.Fatalf("%d leaves: proof of leaf %d accepted at index %d", n, i, other)
+			}
+			truncated := *proof
+			truncated.Siblings = proof.Siblings[:len(proof.Siblings)-1]
+			if core.VerifyMerkleProof(root, leaves[i], &truncated) {
+				t.Fatalf("%d leaves: truncated proof of leaf %d accepted", n, i)
+			}
+		}
+
+		if _, err := core.ComputeMerkleProof(leaves, n); !errors.Is(err, core.ErrInvalidProofIndex) {
+			t.Fatalf("%d leaves: proof past the last leaf: got %v", n, err)
+		}
+	}
+}
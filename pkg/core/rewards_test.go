@@ -0,0 +1,44 @@
+package core_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/types"
+)
+
+func TestBlockRewardPaidToProducerAndDelegators(t *testing.T) {
+	// A single validator with 10000 APX of self-stake and 10% commission
+	c := newTestChain(t, 1, 1, nil)
+	validator := addressOf(c.validators[0])
+	delegator := addressOf(c.users[0])
+	c.mustSucceed(c.tx(c.users[0], core.TxTypeDelegate, core.StakeData{
+		Validator: validator,
+		Amount:    types.ToWei(10000),
+	}))
+
+	validatorBefore := c.account(validator).Balance
+	delegatorBefore := c.account(delegator).Balance
+	c.next()
+
+	// An empty block pays the initial reward. The delegator bonds half of
+	// the stake and gets half of what is left after commission.
+	reward := c.params.InitialReward
+	share := new(big.Int).Sub(reward, new(big.Int).Div(reward, big.NewInt(10)))
+	share.Div(share, big.NewInt(2))
+	if got := new(big.Int).Sub(c.account(delegator).Balance, delegatorBefore); got.Cmp(share) != 0 {
+		t.Fatalf("delegator paid %s, want %s", got, share)
+	}
+	if got := new(big.Int).Sub(c.account(validator).Balance, validatorBefore); got.Cmp(new(big.Int).Sub(reward, share)) != 0 {
+		t.Fatalf("validator paid %s, want %s", got, new(big.Int).Sub(reward, share))
+	}
+
+	delegation, err := c.bc.GetStateDB().GetDelegation(delegator, validator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delegation.Rewards.Cmp(share) < 0 {
+		t.Fatalf("delegation rewards %s do not include the block's %s", delegation.Rewards, share)
+	}
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/apex/pkg/consensus"
+	"github.com/apex/pkg/types"
+)
+
+// slashValidator slashes a validator, in the block at height, for an
+// infraction committed at infractionHeight. Its self-stake and delegations
+// lose the slash fraction, and so does stake that was bonded to it at the
+// infraction but has since started unbonding or been redelegated. The
+// slashed stake is burned and the slash recorded in state.
+func (bc *Blockchain) slashValidator(address types.Address, reason consensus.SlashingReason, infractionHeight, height uint64) error {
+	params, err := bc.stateDB.GetParams()
+	if err != nil {
+		return err
+	}
+	fraction, err := consensus.SlashFraction(params, reason)
+	if err != nil {
+		return err
+	}
+
+	slashes, err := bc.slasher.SlashValidator(address, reason, height)
+	if err != nil {
+		return err
+	}
+
+	// The slashed stake leaves the stakers' accounts
+	total := big.NewInt(0)
+	for _, slash := range slashes {
+		account, err := bc.stateDB.GetAccount(slash.Staker)
+		if err != nil {
+			return err
+		}
+		if !account.SubStake(slash.Amount) {
+			account.Staked = big.NewInt(0)
+		}
+		if err := bc.stateDB.SetAccount(account); err != nil {
+			return err
+		}
+		total.Add(total, slash.Amount)
+	}
+
+	unbonding, err := bc.slashUnbondings(address, infractionHeight, fraction)
+	if err != nil {
+		return err
+	}
+	redelegated, err := bc.slashRedelegations(address, infractionHeight, fraction)
+	if err != nil {
+		return err
+	}
+	total.Add(total, unbonding)
+	total.Add(total, redelegated)
+
+	return bc.slasher.RecordEvent(&types.SlashingEvent{
+		Validator:        address,
+		Reason:           reason.String(),
+		Fraction:         fraction,
+		Amount:           total,
+		InfractionHeight: infractionHeight,
+		Height:           height,
+	})
+}
+
+// slashUnbondings slashes fraction basis points of every unbonding entry of
+// stake bonded to validator that started at or after infractionHeight, when
+// the stake was still bonded. The amount leaves the delegator's locked
+// balance and is burned. It returns the total amount slashed.
+func (bc *Blockchain) slashUnbondings(validator types.Address, infractionHeight, fraction uint64) (*big.Int, error) {
+	unbondings, err := bc.stateDB.GetUnbondingsByValidator(validator)
+	if err != nil {
+		return nil, err
+	}
+
+	total := big.NewInt(0)
+	for _, unbonding := range unbondings {
+		// Stake that left before the infraction was not at stake for it
+		if unbonding.CreationHeight < infractionHeight {
+			continue
+		}
+
+		amount := new(big.Int).Mul(unbonding.Amount, new(big.Int).SetUint64(fraction))
+		amount.Div(amount, big.NewInt(10000))
+		if amount.Sign() == 0 {
+			continue
+		}
+
+		unbonding.Amount.Sub(unbonding.Amount, amount)
+		if unbonding.Amount.Sign() == 0 {
+			err = bc.stateDB.DeleteUnbonding(unbonding)
+		} else {
+			err = bc.stateDB.SetUnbonding(unbonding)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		account, err := bc.stateDB.GetAccount(unbonding.Delegator)
+		if err != nil {
+			return nil, err
+		}
+		account.Locked.Sub(account.Locked, amount)
+		if account.Locked.Sign() < 0 {
+			account.Locked = big.NewInt(0)
+		}
+		if err := bc.stateDB.SetAccount(account); err != nil {
+			return nil, err
+		}
+
+		total.Add(total, amount)
+	}
+	return total, nil
+}
+
+// slashRedelegations slashes fraction basis points of every maturing
+// redelegation away from validator that was created after infractionHeight.
+// The amount is taken from the destination delegation, capped at what is
+// left of it, and burned. It returns the total amount slashed.
+func (bc *Blockchain) slashRedelegations(validator types.Address, infractionHeight, fraction uint64) (*big.Int, error) {
+	redelegations, err := bc.stateDB.GetRedelegationsFromValidator(validator)
+	if err != nil {
+		return nil, err
+	}
+
+	total := big.NewInt(0)
+	for _, redelegation := range redelegations {
+		// Stake that moved before the infraction was not at stake for it
+		if redelegation.CreationHeight < infractionHeight {
+			continue
+		}
+
+		amount := new(big.Int).Mul(redelegation.Amount, new(big.Int).SetUint64(fraction))
+		amount.Div(amount, big.NewInt(10000))
+		if amount.Sign() == 0 {
+			continue
+		}
+
+		delegation, err := bc.stateDB.GetDelegation(redelegation.Delegator, redelegation.ValidatorDst)
+		if err != nil {
+			// Nothing left at the destination
+			continue
+		}
+		if delegation.Amount.Cmp(amount) < 0 {
+			amount.Set(delegation.Amount)
+		}
+
+		delegation.Amount.Sub(delegation.Amount, amount)
+		if delegation.Amount.Sign() == 0 {
+			err = bc.stateDB.DeleteDelegation(redelegation.Delegator, redelegation.ValidatorDst)
+		} else {
+			err = bc.stateDB.SetDelegation(delegation)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		dst, err := bc.stateDB.GetValidator(redelegation.ValidatorDst)
+		if err != nil {
+			return nil, err
+		}
+		dst.SubVotingPower(amount)
+		if err := bc.stateDB.SetValidator(dst); err != nil {
+			return nil, err
+		}
+
+		account, err := bc.stateDB.GetAccount(redelegation.Delegator)
+		if err != nil {
+			return nil, err
+		}
+		if !account.SubStake(amount) {
+			account.Staked = big.NewInt(0)
+		}
+		if err := bc.stateDB.SetAccount(account); err != nil {
+			return nil, err
+		}
+
+		total.Add(total, amount)
+	}
+	return total, nil
+}
+
+// trackSlots records the slot filled by a block as produced and the slots
+// skipped since its parent as missed by the validators scheduled in them,
+// then jails and slashes those whose signing window fell below the minimum.
+// After a long outage only the last EpochLength skipped slots, one round of
+// the schedule, are charged. The caller must hold bc.mu.
+func (bc *Blockchain) trackSlots(header *BlockHeader) error {
+	parent, exists := bc.blocksByHash[header.PreviousHash]
+	if !exists {
+		return ErrUnknownParent
+	}
+
+	slot := bc.slotOf(header.Timestamp)
+	first := bc.slotOf(parent.Header.Timestamp) + 1
+	if length := bc.dpos.Params().EpochLength; slot-first > length {
+		first = slot - length
+	}
+
+	var missed []types.Address
+	seen := make(map[types.Address]bool)
+	for s := first; s < slot; s++ {
+		producer, err := bc.dpos.GetSlotProducer(header.Number, s)
+		if err != nil {
+			continue
+		}
+		if err := bc.validatorMgr.IncrementMissedBlocks(producer.Address, header.Number); err != nil {
+			return err
+		}
+		if !seen[producer.Address] {
+			seen[producer.Address] = true
+			missed = append(missed, producer.Address)
+		}
+	}
+	if err := bc.validatorMgr.IncrementProducedBlocks(header.Validator, header.Number); err != nil {
+		return err
+	}
+
+	for _, address := range missed {
+		if bc.slasher.CheckDowntime(address) {
+			if err := bc.slashValidator(address, consensus.SlashingReasonDowntime, header.Number, header.Number); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
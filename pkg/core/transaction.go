@@ -1,11 +1,12 @@
 package core
 
 import (
+	"crypto/ecdsa"
 	"crypto/sha256"
-	"encoding/json"
 	"math/big"
 	"time"
 
+	"github.com/apex/pkg/crypto"
 	"github.com/apex/pkg/types"
 )
 
@@ -21,34 +22,41 @@ const (
 	TxTypeVote
 	TxTypeCreateValidator
 	TxTypeEditValidator
+	TxTypeRedelegate
+	TxTypeUnjail
 )
 
 // Transaction represents a blockchain transaction
 type Transaction struct {
-	Hash      types.Hash    `json:"hash"`
-	Type      TxType        `json:"type"`
-	From      types.Address `json:"from"`
-	To        types.Address `json:"to"`
-	Value     *big.Int      `json:"value"`
-	Data      []byte        `json:"data"`
-	Nonce     uint64        `json:"nonce"`
-	GasLimit  uint64        `json:"gas_limit"`
-	GasPrice  *big.Int      `json:"gas_price"`
-	Signature types.Signature `json:"signature"`
-	Timestamp time.Time     `json:"timestamp"`
+	Hash                 types.Hash      `json:"hash"`
+	ChainID              string          `json:"chain_id"`
+	Type                 TxType          `json:"type"`
+	From                 types.Address   `json:"from"`
+	To                   types.Address   `json:"to"`
+	Value                *big.Int        `json:"value"`
+	Data                 []byte          `json:"data"`
+	Nonce                uint64          `json:"nonce"`
+	GasLimit             uint64          `json:"gas_limit"`
+	MaxFeePerGas         *big.Int        `json:"max_fee_per_gas"`          // cap on base fee plus tip
+	MaxPriorityFeePerGas *big.Int        `json:"max_priority_fee_per_gas"` // cap on the producer tip
+	Signature            types.Signature `json:"signature"`
+	Timestamp            time.Time       `json:"timestamp"`
 }
 
 // TxReceipt represents a transaction receipt
 type TxReceipt struct {
-	TxHash          types.Hash    `json:"tx_hash"`
-	BlockHash       types.Hash    `json:"block_hash"`
-	BlockNumber     uint64        `json:"block_number"`
-	From            types.Address `json:"from"`
-	To              types.Address `json:"to"`
-	GasUsed         uint64        `json:"gas_used"`
-	Status          uint8         `json:"status"` // 1 = success, 0 = failure
-	Logs            []Log         `json:"logs"`
-	ContractAddress types.Address `json:"contract_address,omitempty"`
+	TxHash            types.Hash    `json:"tx_hash"`
+	BlockHash         types.Hash    `json:"block_hash"`
+	BlockNumber       uint64        `json:"block_number"`
+	TxIndex           uint64        `json:"tx_index"`
+	From              types.Address `json:"from"`
+	To                types.Address `json:"to"`
+	GasUsed           uint64        `json:"gas_used"`
+	EffectiveGasPrice *big.Int      `json:"effective_gas_price"` // base fee plus tip paid per gas
+	Status            uint8         `json:"status"`              // 1 = success, 0 = failure
+	Error             string        `json:"error,omitempty"`
+	Logs              []Log         `json:"logs"`
+	ContractAddress   types.Address `json:"contract_address,omitempty"`
 }
 
 // Log represents an event log
@@ -58,44 +66,45 @@ type Log struct {
 	Data    []byte        `json:"data"`
 }
 
+// ComputeHash computes the receipt hash committed to by the receipts root.
+// Only consensus fields are covered; block hash and error text are not.
+func (r *TxReceipt) ComputeHash() types.Hash {
+	hash := sha256.Sum256(encodeReceipt(r))
+	var receiptHash types.Hash
+	copy(receiptHash[:], hash[:])
+	return receiptHash
+}
+
+// ComputeReceiptsRoot computes the merkle root of a block's receipts
+func ComputeReceiptsRoot(receipts []*TxReceipt) types.Hash {
+	leaves := make([][]byte, len(receipts))
+	for i, receipt := range receipts {
+		hash := receipt.ComputeHash()
+		leaves[i] = hash[:]
+	}
+	return MerkleRoot(leaves)
+}
+
 // NewTransaction creates a new transaction
 func NewTransaction(txType TxType, from, to types.Address, value *big.Int, data []byte, nonce uint64) *Transaction {
 	return &Transaction{
-		Type:      txType,
-		From:      from,
-		To:        to,
-		Value:     value,
-		Data:      data,
-		Nonce:     nonce,
-		GasLimit:  21000, // Base gas
-		GasPrice:  big.NewInt(1000000000), // 1 Gwei default
-		Timestamp: time.Now(),
+		Type:                 txType,
+		From:                 from,
+		To:                   to,
+		Value:                value,
+		Data:                 data,
+		Nonce:                nonce,
+		GasLimit:             IntrinsicGas(txType, data) + executionGasAllowance,
+		MaxFeePerGas:         new(big.Int).Add(new(big.Int).Mul(InitialBaseFee, big.NewInt(2)), DefaultPriorityFee),
+		MaxPriorityFeePerGas: new(big.Int).Set(DefaultPriorityFee),
+		Timestamp:            time.Now(),
 	}
 }
 
-// ComputeHash computes transaction hash
+// ComputeHash computes transaction hash over the canonical encoding of the
+// unsigned transaction fields
 func (tx *Transaction) ComputeHash() types.Hash {
-	data, _ := json.Marshal(struct {
-		Type     TxType
-		From     types.Address
-		To       types.Address
-		Value    *big.Int
-		Data     []byte
-		Nonce    uint64
-		GasLimit uint64
-		GasPrice *big.Int
-	}{
-		Type:     tx.Type,
-		From:     tx.From,
-		To:       tx.To,
-		Value:    tx.Value,
-		Data:     tx.Data,
-		Nonce:    tx.Nonce,
-		GasLimit: tx.GasLimit,
-		GasPrice: tx.GasPrice,
-	})
-	
-	hash := sha256.Sum256(data)
+	hash := sha256.Sum256(tx.signingBytes())
 	var txHash types.Hash
 	copy(txHash[:], hash[:])
 	return txHash
@@ -107,28 +116,94 @@ func (tx *Transaction) Sign(signature types.Signature) {
 	tx.Hash = tx.ComputeHash()
 }
 
-// GetCost returns total transaction cost (value + gas)
+// SignWithKey signs the transaction hash with the sender's private key
+func (tx *Transaction) SignWithKey(privKey *ecdsa.PrivateKey) error {
+	signature, err := crypto.SignHash(tx.ComputeHash(), privKey)
+	if err != nil {
+		return err
+	}
+	tx.Sign(signature)
+	return nil
+}
+
+// Sender recovers the address that signed the transaction. The signed hash
+// covers the chain ID, so a signature is only valid on one chain.
+func (tx *Transaction) Sender() (types.Address, error) {
+	sender, err := crypto.RecoverAddress(tx.ComputeHash(), tx.Signature)
+	if err != nil {
+		return types.Address{}, ErrInvalidSignature
+	}
+	return sender, nil
+}
+
+// VerifySender checks that the transaction belongs to the given chain and
+// was signed by its From address
+func (tx *Transaction) VerifySender(chainID string) error {
+	if tx.ChainID != chainID {
+		return ErrInvalidChainID
+	}
+	sender, err := tx.Sender()
+	if err != nil {
+		return err
+	}
+	if sender != tx.From {
+		return ErrInvalidSender
+	}
+	return nil
+}
+
+// GetCost returns the maximum transaction cost (value + gas at the fee cap)
 func (tx *Transaction) GetCost() *big.Int {
 	cost := new(big.Int).Set(tx.Value)
-	gasCost := new(big.Int).Mul(big.NewInt(int64(tx.GasLimit)), tx.GasPrice)
+	gasCost := new(big.Int).Mul(new(big.Int).SetUint64(tx.GasLimit), tx.MaxFeePerGas)
 	cost.Add(cost, gasCost)
 	return cost
 }
 
+// EffectiveTip returns the tip per gas paid to the producer under baseFee.
+// It is negative if the fee cap does not cover the base fee.
+func (tx *Transaction) EffectiveTip(baseFee *big.Int) *big.Int {
+	tip := new(big.Int).Sub(tx.MaxFeePerGas, baseFee)
+	if tip.Cmp(tx.MaxPriorityFeePerGas) > 0 {
+		tip.Set(tx.MaxPriorityFeePerGas)
+	}
+	return tip
+}
+
+// EffectiveGasPrice returns the price per gas paid under baseFee
+func (tx *Transaction) EffectiveGasPrice(baseFee *big.Int) *big.Int {
+	return new(big.Int).Add(baseFee, tx.EffectiveTip(baseFee))
+}
+
 // Validate performs basic transaction validation
 func (tx *Transaction) Validate() error {
-	if tx.Value.Sign() < 0 {
+	if tx.Value == nil || tx.Value.Sign() < 0 {
 		return ErrInvalidTxValue
 	}
 	if tx.GasLimit == 0 {
 		return ErrInvalidGasLimit
 	}
-	if tx.GasPrice.Sign() <= 0 {
+	if _, ok := GasCostOf(tx.Type); !ok {
+		return ErrInvalidTxType
+	}
+	if tx.GasLimit < IntrinsicGas(tx.Type, tx.Data) {
+		return ErrIntrinsicGas
+	}
+	if tx.MaxFeePerGas == nil || tx.MaxFeePerGas.Sign() <= 0 {
 		return ErrInvalidGasPrice
 	}
+	if tx.MaxPriorityFeePerGas == nil || tx.MaxPriorityFeePerGas.Sign() < 0 {
+		return ErrInvalidTip
+	}
+	if tx.MaxPriorityFeePerGas.Cmp(tx.MaxFeePerGas) > 0 {
+		return ErrTipAboveFeeCap
+	}
 	if len(tx.Signature) == 0 {
 		return ErrMissingSignature
 	}
+	if len(tx.Signature) != crypto.SignatureLength {
+		return ErrInvalidSignature
+	}
 	return nil
 }
 
@@ -138,7 +213,15 @@ type StakeData struct {
 	Amount    *big.Int      `json:"amount"`
 }
 
-// VoteData represents vote transaction data
+// RedelegateData represents redelegate transaction data
+type RedelegateData struct {
+	ValidatorSrc types.Address `json:"validator_src"`
+	ValidatorDst types.Address `json:"validator_dst"`
+	Amount       *big.Int      `json:"amount"`
+}
+
+// VoteData represents vote transaction data. A zero weight withdraws the
+// vote; the weights of a voter's votes may not exceed its staked amount.
 type VoteData struct {
 	Validator types.Address `json:"validator"`
 	Weight    *big.Int      `json:"weight"`
@@ -153,3 +236,12 @@ type CreateValidatorData struct {
 	Website    string   `json:"website"`
 	Details    string   `json:"details"`
 }
+
+// EditValidatorData represents edit validator transaction data; nil fields
+// are left unchanged
+type EditValidatorData struct {
+	Commission *uint64 `json:"commission,omitempty"`
+	Moniker    *string `json:"moniker,omitempty"`
+	Website    *string `json:"website,omitempty"`
+	Details    *string `json:"details,omitempty"`
+}
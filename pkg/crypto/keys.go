@@ -13,9 +13,9 @@ import (
 	"golang.org/x/crypto/ripemd160"
 )
 
-// GenerateKeyPair generates a new ECDSA key pair
+// GenerateKeyPair generates a new secp256k1 key pair
 func GenerateKeyPair() (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
-	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	privKey, err := ecdsa.GenerateKey(btcec.S256(), rand.Reader)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -42,7 +42,9 @@ func PublicKeyToAddress(pubKey *ecdsa.PublicKey) types.Address {
 
 // PrivateKeyToHex exports private key to hex
 func PrivateKeyToHex(privKey *ecdsa.PrivateKey) string {
-	return hex.EncodeToString(privKey.D.Bytes())
+	var bytes [32]byte
+	privKey.D.FillBytes(bytes[:])
+	return hex.EncodeToString(bytes[:])
 }
 
 // HexToPrivateKey imports private key from hex
@@ -51,15 +53,16 @@ func HexToPrivateKey(hexKey string) (*ecdsa.PrivateKey, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(bytes) != 32 {
+		return nil, errors.New("invalid private key length")
+	}
 	
-	privKey := new(ecdsa.PrivateKey)
-	privKey.PublicKey.Curve = elliptic.P256()
-	privKey.D = new(btcec.ModNScalar)
-	privKey.D.SetByteSlice(bytes)
-	
-	privKey.PublicKey.X, privKey.PublicKey.Y = privKey.PublicKey.Curve.ScalarBaseMult(bytes)
+	privKey, _ := btcec.PrivKeyFromBytes(bytes)
+	if privKey.Key.IsZero() {
+		return nil, errors.New("invalid private key")
+	}
 	
-	return privKey, nil
+	return privKey.ToECDSA(), nil
 }
 
 // PublicKeyToBytes serializes public key
@@ -69,12 +72,11 @@ func PublicKeyToBytes(pubKey *ecdsa.PublicKey) []byte {
 
 // BytesToPublicKey deserializes public key
 func BytesToPublicKey(bytes []byte) (*ecdsa.PublicKey, error) {
-	curve := elliptic.P256()
-	x, y := elliptic.Unmarshal(curve, bytes)
-	if x == nil {
+	pubKey, err := btcec.ParsePubKey(bytes)
+	if err != nil {
 		return nil, errors.New("invalid public key")
 	}
-	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	return pubKey.ToECDSA(), nil
 }
 
 // HashData hashes data with SHA256
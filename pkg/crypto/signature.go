@@ -2,69 +2,101 @@ package crypto
 
 import (
 	"crypto/ecdsa"
-	"crypto/rand"
-	"crypto/sha256"
 	"errors"
-	"math/big"
 
 	"github.com/apex/pkg/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+	btcecdsa "github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+// SignatureLength is the length of a recoverable signature: R || S || V,
+// where V is the recovery ID (0 or 1)
+const SignatureLength = 65
+
+// compactRecoveryOffset is the recovery byte offset used by btcec compact
+// signatures for uncompressed public keys
+const compactRecoveryOffset = 27
+
+// Signature errors
+var (
+	ErrInvalidSignature   = errors.New("invalid signature")
+	ErrUnsupportedKeyType = errors.New("private key is not a secp256k1 key")
 )
 
 // SignData signs data with private key
 func SignData(data []byte, privKey *ecdsa.PrivateKey) (types.Signature, error) {
-	hash := sha256.Sum256(data)
-	
-	r, s, err := ecdsa.Sign(rand.Reader, privKey, hash[:])
-	if err != nil {
-		return nil, err
-	}
-	
-	// Serialize signature
-	signature := append(r.Bytes(), s.Bytes()...)
-	return types.Signature(signature), nil
+	return SignHash(HashData(data), privKey)
 }
 
 // VerifySignature verifies signature with public key
 func VerifySignature(data []byte, signature types.Signature, pubKey *ecdsa.PublicKey) bool {
-	if len(signature) < 64 {
-		return false
-	}
-	
-	hash := sha256.Sum256(data)
-	
-	// Deserialize signature
-	r := new(big.Int).SetBytes(signature[:32])
-	s := new(big.Int).SetBytes(signature[32:64])
-	
-	return ecdsa.Verify(pubKey, hash[:], r, s)
+	return VerifyHashSignature(HashData(data), signature, pubKey)
 }
 
-// RecoverPublicKey recovers public key from signature (simplified)
+// RecoverPublicKey recovers the public key that signed data
 func RecoverPublicKey(data []byte, signature types.Signature) (*ecdsa.PublicKey, error) {
-	// This is a simplified implementation
-	// In production, use proper ECDSA recovery like in Ethereum
-	return nil, errors.New("not implemented")
+	return RecoverHashPublicKey(HashData(data), signature)
 }
 
-// SignHash signs a hash directly
+// SignHash signs a hash directly, producing a recoverable secp256k1 signature
 func SignHash(hash types.Hash, privKey *ecdsa.PrivateKey) (types.Signature, error) {
-	r, s, err := ecdsa.Sign(rand.Reader, privKey, hash[:])
+	if privKey == nil || privKey.Curve != btcec.S256() {
+		return nil, ErrUnsupportedKeyType
+	}
+
+	key, _ := btcec.PrivKeyFromBytes(privKey.D.Bytes())
+	compact, err := btcecdsa.SignCompact(key, hash[:], false)
 	if err != nil {
 		return nil, err
 	}
-	
-	signature := append(r.Bytes(), s.Bytes()...)
-	return types.Signature(signature), nil
+
+	// Move the recovery byte from the front to the back
+	signature := make(types.Signature, SignatureLength)
+	copy(signature, compact[1:])
+	signature[64] = compact[0] - compactRecoveryOffset
+	return signature, nil
 }
 
 // VerifyHashSignature verifies hash signature
 func VerifyHashSignature(hash types.Hash, signature types.Signature, pubKey *ecdsa.PublicKey) bool {
-	if len(signature) < 64 {
+	if pubKey == nil {
+		return false
+	}
+	recovered, err := RecoverHashPublicKey(hash, signature)
+	if err != nil {
 		return false
 	}
-	
-	r := new(big.Int).SetBytes(signature[:32])
-	s := new(big.Int).SetBytes(signature[32:64])
-	
-	return ecdsa.Verify(pubKey, hash[:], r, s)
+	return recovered.X.Cmp(pubKey.X) == 0 && recovered.Y.Cmp(pubKey.Y) == 0
+}
+
+// RecoverHashPublicKey recovers the public key that signed hash. Only
+// canonical (low-S) signatures are accepted.
+func RecoverHashPublicKey(hash types.Hash, signature types.Signature) (*ecdsa.PublicKey, error) {
+	if len(signature) != SignatureLength || signature[64] > 1 {
+		return nil, ErrInvalidSignature
+	}
+
+	var s btcec.ModNScalar
+	if overflow := s.SetByteSlice(signature[32:64]); overflow || s.IsOverHalfOrder() {
+		return nil, ErrInvalidSignature
+	}
+
+	compact := make([]byte, SignatureLength)
+	compact[0] = signature[64] + compactRecoveryOffset
+	copy(compact[1:], signature[:64])
+
+	pubKey, _, err := btcecdsa.RecoverCompact(compact, hash[:])
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return pubKey.ToECDSA(), nil
+}
+
+// RecoverAddress recovers the address of the key that signed hash
+func RecoverAddress(hash types.Hash, signature types.Signature) (types.Address, error) {
+	pubKey, err := RecoverHashPublicKey(hash, signature)
+	if err != nil {
+		return types.Address{}, err
+	}
+	return PublicKeyToAddress(pubKey), nil
 }
@@ -0,0 +1,100 @@
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+func signedHash(t *testing.T) (*ecdsa.PrivateKey, types.Hash, types.Signature) {
+	t.Helper()
+
+	key, _, err := crypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := crypto.HashData([]byte("message"))
+	signature, err := crypto.SignHash(hash, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, hash, signature
+}
+
+func TestRecoverAddressOfSigner(t *testing.T) {
+	key, hash, signature := signedHash(t)
+
+	sender, err := crypto.RecoverAddress(hash, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender != crypto.PublicKeyToAddress(&key.PublicKey) {
+		t.Fatal("recovered address is not the signer's")
+	}
+	if !crypto.VerifyHashSignature(hash, signature, &key.PublicKey) {
+		t.Fatal("signature rejected for the signer's key")
+	}
+
+	other := crypto.HashData([]byte("other message"))
+	if recovered, err := crypto.RecoverAddress(other, signature); err == nil && recovered == sender {
+		t.Fatal("signature recovers the signer for another hash")
+	}
+}
+
+func TestRecoverRejectsHighS(t *testing.T) {
+	key, hash, signature := signedHash(t)
+
+	// (r, n-s) with the other recovery ID is the same signature in its
+	// malleable high-S form
+	n := btcec.S256().N
+	s := new(big.Int).SetBytes(signature[32:64])
+	malleable := append(types.Signature(nil), signature...)
+	new(big.Int).Sub(n, s).FillBytes(malleable[32:64])
+	malleable[64] ^= 1
+
+	if _, err := crypto.RecoverHashPublicKey(hash, malleable); !errors.Is(err, crypto.ErrInvalidSignature) {
+		t.Fatalf("high-S signature: got %v, want %v", err, crypto.ErrInvalidSignature)
+	}
+	if crypto.VerifyHashSignature(hash, malleable, &key.PublicKey) {
+		t.Fatal("high-S signature verified")
+	}
+}
+
+func TestRecoverRejectsMalformedSignatures(t *testing.T) {
+	_, hash, signature := signedHash(t)
+
+	badRecovery := append(types.Signature(nil), signature...)
+	badRecovery[64] = 2
+	overflow := append(types.Signature(nil), signature...)
+	copy(overflow[32:64], bytes.Repeat([]byte{0xff}, 32))
+
+	cases := map[string]types.Signature{
+		"short":          signature[:64],
+		"long":           append(append(types.Signature(nil), signature...), 0),
+		"recovery id":    badRecovery,
+		"s out of range": overflow,
+	}
+	for name, malformed := range cases {
+		if _, err := crypto.RecoverHashPublicKey(hash, malformed); !errors.Is(err, crypto.ErrInvalidSignature) {
+			t.Errorf("%s: got %v, want %v", name, err, crypto.ErrInvalidSignature)
+		}
+	}
+}
+
+func TestSignHashRejectsOtherCurves(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := crypto.SignHash(types.Hash{}, key); !errors.Is(err, crypto.ErrUnsupportedKeyType) {
+		t.Fatalf("got %v, want %v", err, crypto.ErrUnsupportedKeyType)
+	}
+}
@@ -0,0 +1,388 @@
+package genesis
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/types"
+)
+
+// Genesis is the genesis configuration of a chain, as stored in genesis.json
+type Genesis struct {
+	ChainID         string          `json:"chain_id"`
+	GenesisTime     time.Time       `json:"genesis_time"`
+	TotalSupply     string          `json:"total_supply"`
+	Validators      []Validator     `json:"initial_validators"`
+	Accounts        []Account       `json:"initial_accounts"`
+	ConsensusParams ConsensusParams `json:"consensus_params"`
+	RewardParams    RewardParams    `json:"reward_params"`
+}
+
+// Validator is a genesis validator; its voting power is bonded as self-stake
+type Validator struct {
+	Address     string `json:"address"`
+	PublicKey   string `json:"public_key"`
+	VotingPower string `json:"voting_power"`
+	Commission  uint64 `json:"commission"`
+	Moniker     string `json:"moniker"`
+	Website     string `json:"website"`
+	Details     string `json:"details"`
+}
+
+// Account is a genesis account balance
+type Account struct {
+	Address     string `json:"address"`
+	Balance     string `json:"balance"`
+	Description string `json:"description"`
+}
+
+// ConsensusParams holds the consensus parameters. Optional fields fall back
+// to the defaults when omitted.
+type ConsensusParams struct {
+	BlockTime                 int     `json:"block_time"`
+	MaxValidators             int     `json:"max_validators"`
+	EpochLength               uint64  `json:"epoch_length,omitempty"`
+	ValidatorSetDelay         uint64  `json:"validator_set_delay,omitempty"`
+	WeightedSchedule          *bool   `json:"weighted_schedule,omitempty"`
+	MinStake                  string  `json:"min_stake"`
+	MinDelegation             string  `json:"min_delegation,omitempty"`
+	UnbondingPeriod           uint64  `json:"unbonding_period"`
+	SignedSlotsWindow         uint64  `json:"signed_slots_window,omitempty"`
+	MinSignedPerWindow        float64 `json:"min_signed_per_window,omitempty"`
+	DowntimeJailDuration      uint64  `json:"downtime_jail_duration,omitempty"`
+	MaxCommissionChangeRate   uint64  `json:"max_commission_change_rate,omitempty"`
+	SlashFractionDoubleSign   float64 `json:"slash_fraction_double_sign"`
+	SlashFractionDowntime     float64 `json:"slash_fraction_downtime"`
+	SlashFractionInvalidBlock float64 `json:"slash_fraction_invalid_block,omitempty"`
+}
+
+// RewardParams holds the block reward parameters
+type RewardParams struct {
+	InitialReward string `json:"initial_reward"`
+	HalvingPeriod uint64 `json:"halving_period"`
+	MinimumReward string `json:"minimum_reward"`
+}
+
+// Load reads and validates a genesis file
+func Load(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a genesis configuration
+func Parse(data []byte) (*Genesis, error) {
+	var g Genesis
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("parse genesis: %w", err)
+	}
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Validate checks that the genesis configuration is complete and consistent
+func (g *Genesis) Validate() error {
+	if g.ChainID == "" {
+		return errors.New("genesis: chain_id is required")
+	}
+	if g.GenesisTime.IsZero() {
+		return errors.New("genesis: genesis_time is required")
+	}
+	totalSupply, err := parseAmount(g.TotalSupply)
+	if err != nil {
+		return fmt.Errorf("genesis: total_supply: %w", err)
+	}
+
+	if len(g.Validators) == 0 {
+		return errors.New("genesis: at least one validator is required")
+	}
+	if err := g.ConsensusParams.validate(); err != nil {
+		return err
+	}
+	if err := g.RewardParams.validate(); err != nil {
+		return err
+	}
+	if _, err := g.ToValidators(); err != nil {
+		return err
+	}
+	accounts, err := g.ToAccounts()
+	if err != nil {
+		return err
+	}
+
+	allocated := big.NewInt(0)
+	for _, account := range accounts {
+		allocated.Add(allocated, account.Balance)
+		allocated.Add(allocated, account.Staked)
+	}
+	if allocated.Cmp(totalSupply) > 0 {
+		return fmt.Errorf("genesis: allocated %s exceeds total supply %s", allocated, totalSupply)
+	}
+	return nil
+}
+
+// ToParams converts the consensus and reward parameters into the chain
+// parameters stored in state
+func (g *Genesis) ToParams() (*types.Params, error) {
+	params := types.DefaultParams()
+	c, r := g.ConsensusParams, g.RewardParams
+
+	params.BlockTime = uint64(c.BlockTime)
+	params.MaxValidators = c.MaxValidators
+	if c.EpochLength != 0 {
+		params.EpochLength = c.EpochLength
+	}
+	if c.ValidatorSetDelay != 0 {
+		params.ValidatorSetDelay = c.ValidatorSetDelay
+	}
+	if c.WeightedSchedule != nil {
+		params.WeightedSchedule = *c.WeightedSchedule
+	}
+	params.UnbondingPeriod = c.UnbondingPeriod
+	if c.SignedSlotsWindow != 0 {
+		params.SignedSlotsWindow = c.SignedSlotsWindow
+	}
+	if c.MinSignedPerWindow != 0 {
+		params.MinSignedPerWindow = toBasisPoints(c.MinSignedPerWindow)
+	}
+	if c.DowntimeJailDuration != 0 {
+		params.DowntimeJailDuration = c.DowntimeJailDuration
+	}
+	if c.MaxCommissionChangeRate != 0 {
+		params.MaxCommissionChangeRate = c.MaxCommissionChangeRate
+	}
+	params.SlashFractionDoubleSign = toBasisPoints(c.SlashFractionDoubleSign)
+	params.SlashFractionDowntime = toBasisPoints(c.SlashFractionDowntime)
+	if c.SlashFractionInvalidBlock != 0 {
+		params.SlashFractionInvalidBlock = toBasisPoints(c.SlashFractionInvalidBlock)
+	}
+	params.HalvingPeriod = r.HalvingPeriod
+
+	var err error
+	if params.MinStake, err = parseAmount(c.MinStake); err != nil {
+		return nil, fmt.Errorf("genesis: min_stake: %w", err)
+	}
+	if c.MinDelegation != "" {
+		if params.MinDelegation, err = parseAmount(c.MinDelegation); err != nil {
+			return nil, fmt.Errorf("genesis: min_delegation: %w", err)
+		}
+	}
+	if params.InitialReward, err = parseAmount(r.InitialReward); err != nil {
+		return nil, fmt.Errorf("genesis: initial_reward: %w", err)
+	}
+	if params.MinimumReward, err = parseAmount(r.MinimumReward); err != nil {
+		return nil, fmt.Errorf("genesis: minimum_reward: %w", err)
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("genesis: %w", err)
+	}
+	return params, nil
+}
+
+// ToValidators converts the genesis validators
+func (g *Genesis) ToValidators() ([]*types.Validator, error) {
+	params, err := g.ToParams()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[types.Address]bool)
+
+	validators := make([]*types.Validator, 0, len(g.Validators))
+	for i, v := range g.Validators {
+		addr, err := parseAddress(v.Address)
+		if err != nil {
+			return nil, fmt.Errorf("genesis: validator %d address: %w", i, err)
+		}
+		if seen[addr] {
+			return nil, fmt.Errorf("genesis: duplicate validator %s", v.Address)
+		}
+		seen[addr] = true
+
+		pubKey, err := parseHex(v.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("genesis: validator %d public key: %w", i, err)
+		}
+		key, err := crypto.BytesToPublicKey(pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("genesis: validator %d public key: %w", i, err)
+		}
+		if crypto.PublicKeyToAddress(key) != addr {
+			return nil, fmt.Errorf("genesis: validator %d public key does not match address", i)
+		}
+
+		stake, err := parseAmount(v.VotingPower)
+		if err != nil {
+			return nil, fmt.Errorf("genesis: validator %d voting power: %w", i, err)
+		}
+		if stake.Cmp(params.MinStake) < 0 {
+			return nil, fmt.Errorf("genesis: validator %d stake below minimum", i)
+		}
+		if v.Commission > 10000 {
+			return nil, fmt.Errorf("genesis: validator %d commission above 100%%", i)
+		}
+
+		description := types.ValidatorDescription{
+			Moniker: v.Moniker,
+			Website: v.Website,
+			Details: v.Details,
+		}
+		if err := description.Validate(); err != nil {
+			return nil, fmt.Errorf("genesis: validator %d description: %w", i, err)
+		}
+
+		validator := types.NewValidator(addr, pubKey, stake, v.Commission)
+		validator.Description = description
+		validators = append(validators, validator)
+	}
+	return validators, nil
+}
+
+// ToAccounts converts the genesis accounts. Validators' self-stake is
+// recorded as staked on their accounts, which are created if missing.
+func (g *Genesis) ToAccounts() ([]*types.Account, error) {
+	accounts := make([]*types.Account, 0, len(g.Accounts)+len(g.Validators))
+	byAddress := make(map[types.Address]*types.Account)
+
+	for i, a := range g.Accounts {
+		addr, err := parseAddress(a.Address)
+		if err != nil {
+			return nil, fmt.Errorf("genesis: account %d address: %w", i, err)
+		}
+		if byAddress[addr] != nil {
+			return nil, fmt.Errorf("genesis: duplicate account %s", a.Address)
+		}
+		balance, err := parseAmount(a.Balance)
+		if err != nil {
+			return nil, fmt.Errorf("genesis: account %d balance: %w", i, err)
+		}
+
+		account := types.NewAccount(addr)
+		account.Balance = balance
+		byAddress[addr] = account
+		accounts = append(accounts, account)
+	}
+
+	for i, v := range g.Validators {
+		addr, err := parseAddress(v.Address)
+		if err != nil {
+			return nil, fmt.Errorf("genesis: validator %d address: %w", i, err)
+		}
+		stake, err := parseAmount(v.VotingPower)
+		if err != nil {
+			return nil, fmt.Errorf("genesis: validator %d voting power: %w", i, err)
+		}
+
+		account := byAddress[addr]
+		if account == nil {
+			account = types.NewAccount(addr)
+			byAddress[addr] = account
+			accounts = append(accounts, account)
+		}
+		account.AddStake(stake)
+	}
+	return accounts, nil
+}
+
+// InitChain initializes a new chain with this genesis, or checks that an
+// existing chain was created from it
+func (g *Genesis) InitChain(bc *core.Blockchain) error {
+	if bc.ChainID() != g.ChainID {
+		return fmt.Errorf("genesis: chain id %q does not match %q", g.ChainID, bc.ChainID())
+	}
+
+	params, err := g.ToParams()
+	if err != nil {
+		return err
+	}
+	validators, err := g.ToValidators()
+	if err != nil {
+		return err
+	}
+	accounts, err := g.ToAccounts()
+	if err != nil {
+		return err
+	}
+
+	return bc.InitGenesis(g.GenesisTime, params, validators, accounts)
+}
+
+func (p ConsensusParams) validate() error {
+	if p.BlockTime <= 0 {
+		return errors.New("genesis: block_time must be positive")
+	}
+	if p.MaxValidators <= 0 {
+		return errors.New("genesis: max_validators must be positive")
+	}
+	if _, err := parseAmount(p.MinStake); err != nil {
+		return fmt.Errorf("genesis: min_stake: %w", err)
+	}
+	if p.SlashFractionDoubleSign < 0 || p.SlashFractionDoubleSign > 1 {
+		return errors.New("genesis: slash_fraction_double_sign must be between 0 and 1")
+	}
+	if p.SlashFractionDowntime < 0 || p.SlashFractionDowntime > 1 {
+		return errors.New("genesis: slash_fraction_downtime must be between 0 and 1")
+	}
+	if p.SlashFractionInvalidBlock < 0 || p.SlashFractionInvalidBlock > 1 {
+		return errors.New("genesis: slash_fraction_invalid_block must be between 0 and 1")
+	}
+	return nil
+}
+
+func (p RewardParams) validate() error {
+	if _, err := parseAmount(p.InitialReward); err != nil {
+		return fmt.Errorf("genesis: initial_reward: %w", err)
+	}
+	if _, err := parseAmount(p.MinimumReward); err != nil {
+		return fmt.Errorf("genesis: minimum_reward: %w", err)
+	}
+	if p.HalvingPeriod == 0 {
+		return errors.New("genesis: halving_period must be positive")
+	}
+	return nil
+}
+
+// toBasisPoints converts a fraction between 0 and 1 to basis points
+func toBasisPoints(f float64) uint64 {
+	return uint64(math.Round(f * 10000))
+}
+
+// parseAmount parses a non-negative decimal amount in wei
+func parseAmount(s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+// parseAddress parses a 0x-prefixed or bare hex address
+func parseAddress(s string) (types.Address, error) {
+	var addr types.Address
+	b, err := parseHex(s)
+	if err != nil {
+		return addr, err
+	}
+	if len(b) != len(addr) {
+		return addr, fmt.Errorf("invalid address length %d", len(b))
+	}
+	copy(addr[:], b)
+	return addr, nil
+}
+
+func parseHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
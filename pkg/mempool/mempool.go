@@ -2,6 +2,7 @@ package mempool
 
 import (
 	"errors"
+	"math/big"
 	"sync"
 
 	"github.com/apex/pkg/core"
@@ -13,15 +14,17 @@ type Mempool struct {
 	transactions map[types.Hash]*core.Transaction
 	queue        *PriorityQueue
 	maxSize      int
+	chainID      string
 	mu           sync.RWMutex
 }
 
-// NewMempool creates a new mempool
-func NewMempool(maxSize int) *Mempool {
+// NewMempool creates a new mempool accepting transactions for chainID
+func NewMempool(maxSize int, chainID string) *Mempool {
 	return &Mempool{
 		transactions: make(map[types.Hash]*core.Transaction),
-		queue:        NewPriorityQueue(),
+		queue:        NewPriorityQueue(core.InitialBaseFee),
 		maxSize:      maxSize,
+		chainID:      chainID,
 	}
 }
 
@@ -40,10 +43,15 @@ func (m *Mempool) AddTransaction(tx *core.Transaction) error {
 		return err
 	}
 	
+	// Reject transactions not signed by their sender
+	if err := tx.VerifySender(m.chainID); err != nil {
+		return err
+	}
+	
 	// Check mempool size
 	if len(m.transactions) >= m.maxSize {
 		// Remove lowest priority transaction
-		if lowest := m.queue.Pop(); lowest != nil {
+		if lowest := m.queue.PopLowest(); lowest != nil {
 			delete(m.transactions, lowest.Hash)
 		}
 	}
@@ -87,6 +95,58 @@ func (m *Mempool) GetTransactions(limit int) []*core.Transaction {
 	return m.queue.Top(limit)
 }
 
+// SetBaseFee orders pending transactions by the tip they pay under the base
+// fee of the next block; call it whenever a block is added
+func (m *Mempool) SetBaseFee(baseFee *big.Int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.queue.SetBaseFee(baseFee)
+}
+
+// FollowChain sets the base fee to that of the block after the chain's head.
+// Whenever the head changes, it updates the base fee and removes the
+// transactions included in the blocks that became canonical.
+func (m *Mempool) FollowChain(bc *core.Blockchain) {
+	var mu sync.Mutex
+	last := bc.GetLatestBlock()
+	m.SetBaseFee(core.CalcBaseFee(last.Header))
+	
+	bc.SubscribeNewHead(func(head *core.Block) {
+		mu.Lock()
+		defer mu.Unlock()
+		
+		var included []types.Hash
+		for _, block := range connectedBlocks(bc, last, head) {
+			for _, tx := range block.Transactions {
+				included = append(included, tx.Hash)
+			}
+		}
+		m.RemoveTransactions(included)
+		last = head
+		m.SetBaseFee(core.CalcBaseFee(head.Header))
+	})
+}
+
+// connectedBlocks returns the blocks from head back to its common ancestor
+// with the previous head last, which became canonical when the head moved
+func connectedBlocks(bc *core.Blockchain, last, head *core.Block) []*core.Block {
+	var blocks []*core.Block
+	for head.Hash != last.Hash {
+		var err error
+		if last.Header.Number >= head.Header.Number {
+			last, err = bc.GetBlockByHash(last.Header.PreviousHash)
+		} else {
+			blocks = append(blocks, head)
+			head, err = bc.GetBlockByHash(head.Header.PreviousHash)
+		}
+		if err != nil {
+			break
+		}
+	}
+	return blocks
+}
+
 // Size returns current mempool size
 func (m *Mempool) Size() int {
 	m.mu.RLock()
@@ -101,7 +161,7 @@ func (m *Mempool) Clear() {
 	defer m.mu.Unlock()
 	
 	m.transactions = make(map[types.Hash]*core.Transaction)
-	m.queue = NewPriorityQueue()
+	m.queue = NewPriorityQueue(m.queue.items.baseFee)
 }
 
 // Has checks if transaction exists in mempool
@@ -0,0 +1,162 @@
+package mempool_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/apex/pkg/consensus"
+	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/crypto"
+	"github.com/apex/pkg/mempool"
+	"github.com/apex/pkg/storage"
+	"github.com/apex/pkg/types"
+)
+
+const testChainID = "apex-test-1"
+
+// newTestChain starts a chain produced by a single validator with 100 APX to
+// spend
+func newTestChain(t *testing.T) (*core.Blockchain, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	db, err := storage.NewDatabase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	stateDB, err := storage.NewStateDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := core.NewBlockchain(testChainID, stateDB, storage.NewBlockStore(db), consensus.NewDPoS(stateDB))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := newKey(t)
+	params := types.DefaultParams()
+	stake := new(big.Int).Set(params.MinStake)
+	validator := types.NewValidator(crypto.PublicKeyToAddress(&key.PublicKey), crypto.PublicKeyToBytes(&key.PublicKey), stake, 1000)
+	account := types.NewAccount(validator.Address)
+	account.Balance = types.ToWei(100)
+	account.AddStake(stake)
+	genesisTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := bc.InitGenesis(genesisTime, params, []*types.Validator{validator}, []*types.Account{account}); err != nil {
+		t.Fatal(err)
+	}
+	return bc, key
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, _, err := crypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// addEmptyBlock has the validator produce and add an empty block in the
+// slot after the head
+func addEmptyBlock(t *testing.T, bc *core.Blockchain, key *ecdsa.PrivateKey) {
+	t.Helper()
+
+	addBlock(t, bc, key, nil)
+}
+
+// addBlock has the validator produce and add a block with txs in the slot
+// after the head
+func addBlock(t *testing.T, bc *core.Blockchain, key *ecdsa.PrivateKey, txs []*core.Transaction) *core.Block {
+	t.Helper()
+
+	params, err := bc.GetParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockTime := time.Duration(params.BlockTime) * time.Second
+	block, err := bc.ProduceBlock(key, txs, bc.GetLatestBlock().Header.Timestamp.Add(blockTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+// signedTx returns a transfer paying at most maxFee and tip gwei per gas
+func signedTx(t *testing.T, maxFee, tip int64) *core.Transaction {
+	t.Helper()
+
+	key := newKey(t)
+	tx := core.NewTransaction(core.TxTypeTransfer, crypto.PublicKeyToAddress(&key.PublicKey), types.Address{1}, big.NewInt(1), nil, 0)
+	tx.ChainID = testChainID
+	tx.MaxFeePerGas = new(big.Int).Mul(big.NewInt(maxFee), big.NewInt(1_000_000))
+	tx.MaxPriorityFeePerGas = new(big.Int).Mul(big.NewInt(tip), big.NewInt(1_000_000))
+	if err := tx.SignWithKey(key); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestMempoolFollowsChainBaseFee(t *testing.T) {
+	bc, key := newTestChain(t)
+	pool := mempool.NewMempool(10, testChainID)
+
+	// Fees in thousandths of a gwei. At a base fee of 0.875 gwei, the next
+	// after genesis, lowTip pays the higher tip; once an empty block lowers
+	// the base fee to 0.765625 gwei, highTip does.
+	highTip := signedTx(t, 900, 500)
+	lowTip := signedTx(t, 1000, 100)
+	for _, tx := range []*core.Transaction{highTip, lowTip} {
+		if err := pool.AddTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pool.FollowChain(bc)
+	if top := pool.GetTransactions(1)[0]; top.Hash != lowTip.Hash {
+		t.Fatal("pool not ordered by the base fee of the next block")
+	}
+
+	addEmptyBlock(t, bc, key)
+	if top := pool.GetTransactions(1)[0]; top.Hash != highTip.Hash {
+		t.Fatal("pool not reordered after a block was added")
+	}
+}
+
+func TestMempoolRejectsTransactionWithoutValue(t *testing.T) {
+	pool := mempool.NewMempool(10, testChainID)
+	tx := signedTx(t, 1000, 100)
+	tx.Value = nil
+	tx.Hash = tx.ComputeHash()
+
+	if err := pool.AddTransaction(tx); !errors.Is(err, core.ErrInvalidTxValue) {
+		t.Fatalf("got %v, want %v", err, core.ErrInvalidTxValue)
+	}
+}
+
+func TestMempoolEvictsIncludedTransactions(t *testing.T) {
+	bc, key := newTestChain(t)
+	pool := mempool.NewMempool(10, testChainID)
+	pool.FollowChain(bc)
+
+	tx := core.NewTransaction(core.TxTypeTransfer, crypto.PublicKeyToAddress(&key.PublicKey), types.Address{1}, big.NewInt(1), nil, 0)
+	tx.ChainID = testChainID
+	if err := tx.SignWithKey(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.AddTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	block := addBlock(t, bc, key, pool.GetTransactions(10))
+	if len(block.Transactions) != 1 {
+		t.Fatal("transaction not included in the block")
+	}
+	if pool.Has(tx.Hash) {
+		t.Fatal("included transaction still in the pool")
+	}
+}
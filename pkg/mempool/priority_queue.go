@@ -3,24 +3,35 @@ package mempool
 import (
 	"container/heap"
 	"math/big"
+	"sort"
 
 	"github.com/apex/pkg/core"
 )
 
-// PriorityQueue implements a priority queue for transactions
+// PriorityQueue implements a priority queue for transactions ordered by the
+// tip they pay under the current base fee
 type PriorityQueue struct {
 	items txHeap
 }
 
 // NewPriorityQueue creates a new priority queue
-func NewPriorityQueue() *PriorityQueue {
+func NewPriorityQueue(baseFee *big.Int) *PriorityQueue {
 	pq := &PriorityQueue{
-		items: make(txHeap, 0),
+		items: txHeap{
+			txs:     make([]*core.Transaction, 0),
+			baseFee: baseFee,
+		},
 	}
 	heap.Init(&pq.items)
 	return pq
 }
 
+// SetBaseFee re-orders the queue for a new base fee
+func (pq *PriorityQueue) SetBaseFee(baseFee *big.Int) {
+	pq.items.baseFee = baseFee
+	heap.Init(&pq.items)
+}
+
 // Push adds a transaction to the queue
 func (pq *PriorityQueue) Push(tx *core.Transaction) {
 	heap.Push(&pq.items, tx)
@@ -28,29 +39,45 @@ func (pq *PriorityQueue) Push(tx *core.Transaction) {
 
 // Pop removes and returns the highest priority transaction
 func (pq *PriorityQueue) Pop() *core.Transaction {
-	if len(pq.items) == 0 {
+	if len(pq.items.txs) == 0 {
 		return nil
 	}
 	return heap.Pop(&pq.items).(*core.Transaction)
 }
 
-// Top returns top N transactions without removing them
-func (pq *PriorityQueue) Top(n int) []*core.Transaction {
-	if n > len(pq.items) {
-		n = len(pq.items)
+// PopLowest removes and returns the lowest priority transaction
+func (pq *PriorityQueue) PopLowest() *core.Transaction {
+	if len(pq.items.txs) == 0 {
+		return nil
 	}
 	
-	result := make([]*core.Transaction, n)
-	for i := 0; i < n; i++ {
-		result[i] = pq.items[i]
+	// The minimum of a max-heap is one of its leaves
+	lowest := len(pq.items.txs) / 2
+	for i := lowest + 1; i < len(pq.items.txs); i++ {
+		if pq.items.Less(lowest, i) {
+			lowest = i
+		}
 	}
+	return heap.Remove(&pq.items, lowest).(*core.Transaction)
+}
+
+// Top returns top N transactions by priority without removing them
+func (pq *PriorityQueue) Top(n int) []*core.Transaction {
+	sorted := txHeap{
+		txs:     append([]*core.Transaction(nil), pq.items.txs...),
+		baseFee: pq.items.baseFee,
+	}
+	sort.Sort(sorted)
 	
-	return result
+	if n > len(sorted.txs) {
+		n = len(sorted.txs)
+	}
+	return sorted.txs[:n]
 }
 
 // Remove removes a specific transaction
 func (pq *PriorityQueue) Remove(tx *core.Transaction) {
-	for i, item := range pq.items {
+	for i, item := range pq.items.txs {
 		if item.Hash == tx.Hash {
 			heap.Remove(&pq.items, i)
 			return
@@ -60,40 +87,43 @@ func (pq *PriorityQueue) Remove(tx *core.Transaction) {
 
 // Len returns queue length
 func (pq *PriorityQueue) Len() int {
-	return len(pq.items)
+	return len(pq.items.txs)
 }
 
 // txHeap implements heap.Interface for transactions
-type txHeap []*core.Transaction
+type txHeap struct {
+	txs     []*core.Transaction
+	baseFee *big.Int
+}
 
 func (h txHeap) Len() int {
-	return len(h)
+	return len(h.txs)
 }
 
 func (h txHeap) Less(i, j int) bool {
-	// Higher gas price = higher priority
-	return h[i].GasPrice.Cmp(h[j].GasPrice) > 0
+	// Higher effective tip = higher priority
+	return h.txs[i].EffectiveTip(h.baseFee).Cmp(h.txs[j].EffectiveTip(h.baseFee)) > 0
 }
 
 func (h txHeap) Swap(i, j int) {
-	h[i], h[j] = h[j], h[i]
+	h.txs[i], h.txs[j] = h.txs[j], h.txs[i]
 }
 
 func (h *txHeap) Push(x interface{}) {
-	*h = append(*h, x.(*core.Transaction))
+	h.txs = append(h.txs, x.(*core.Transaction))
 }
 
 func (h *txHeap) Pop() interface{} {
-	old := *h
+	old := h.txs
 	n := len(old)
 	item := old[n-1]
-	*h = old[0 : n-1]
+	h.txs = old[0 : n-1]
 	return item
 }
 
-// CalculatePriority calculates transaction priority
-func CalculatePriority(tx *core.Transaction) *big.Int {
-	// Priority = gas price * gas limit
-	priority := new(big.Int).Mul(tx.GasPrice, big.NewInt(int64(tx.GasLimit)))
+// CalculatePriority calculates transaction priority under baseFee
+func CalculatePriority(tx *core.Transaction, baseFee *big.Int) *big.Int {
+	// Priority = effective tip * gas limit
+	priority := new(big.Int).Mul(tx.EffectiveTip(baseFee), new(big.Int).SetUint64(tx.GasLimit))
 	return priority
 }
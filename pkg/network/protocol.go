@@ -1,10 +1,14 @@
 package network
 
 import (
-	"encoding/json"
+	"crypto/ecdsa"
+	"encoding"
+	"encoding/binary"
+	"errors"
 	"io"
 
 	"github.com/apex/pkg/core"
+	"github.com/apex/pkg/crypto"
 	"github.com/libp2p/go-libp2p/core/network"
 	"go.uber.org/zap"
 )
@@ -20,14 +24,25 @@ const (
 	MsgTypeBlockHeaders
 	MsgTypeGetState
 	MsgTypeState
+	MsgTypePrecommit
+	MsgTypeFinalityCertificate
+	MsgTypeEvidence
 )
 
-// Message represents a network message
+// maxMessageSize bounds the payload of a single network message
+const maxMessageSize = 32 << 20
+
+// Message represents a network message. On the wire it is a type byte
+// followed by a uint32 big-endian payload length and the payload, which
+// uses the canonical binary encoding of pkg/core.
 type Message struct {
 	Type MessageType `json:"type"`
 	Data []byte      `json:"data"`
 }
 
+// ErrMessageTooLarge is returned when a peer announces an oversized message
+var ErrMessageTooLarge = errors.New("message too large")
+
 // Protocol handles network protocol messages
 type Protocol struct {
 	blockchain *core.Blockchain
@@ -49,9 +64,8 @@ func (p *Protocol) HandleStream(stream network.Stream) {
 	defer stream.Close()
 	
 	// Read message
-	var msg Message
-	decoder := json.NewDecoder(stream)
-	if err := decoder.Decode(&msg); err != nil {
+	msg, err := readMessage(stream)
+	if err != nil {
 		if err != io.EOF {
 			p.logger.Error("Failed to decode message", zap.Error(err))
 		}
@@ -68,6 +82,12 @@ func (p *Protocol) HandleStream(stream network.Stream) {
 		p.handleGetBlocks(msg.Data, stream)
 	case MsgTypeGetBlockHeaders:
 		p.handleGetBlockHeaders(msg.Data, stream)
+	case MsgTypePrecommit:
+		p.handlePrecommit(msg.Data, stream)
+	case MsgTypeFinalityCertificate:
+		p.handleFinalityCertificate(msg.Data, stream)
+	case MsgTypeEvidence:
+		p.handleEvidence(msg.Data, stream)
 	default:
 		p.logger.Warn("Unknown message type", zap.Uint8("type", uint8(msg.Type)))
 	}
@@ -76,7 +96,7 @@ func (p *Protocol) HandleStream(stream network.Stream) {
 // handleBlock handles incoming block messages
 func (p *Protocol) handleBlock(data []byte, stream network.Stream) {
 	var block core.Block
-	if err := json.Unmarshal(data, &block); err != nil {
+	if err := block.UnmarshalBinary(data); err != nil {
 		p.logger.Error("Failed to unmarshal block", zap.Error(err))
 		return
 	}
@@ -96,7 +116,7 @@ func (p *Protocol) handleBlock(data []byte, stream network.Stream) {
 // handleTransaction handles incoming transaction messages
 func (p *Protocol) handleTransaction(data []byte, stream network.Stream) {
 	var tx core.Transaction
-	if err := json.Unmarshal(data, &tx); err != nil {
+	if err := tx.UnmarshalBinary(data); err != nil {
 		p.logger.Error("Failed to unmarshal transaction", zap.Error(err))
 		return
 	}
@@ -109,24 +129,20 @@ func (p *Protocol) handleTransaction(data []byte, stream network.Stream) {
 
 // handleGetBlocks handles block requests
 func (p *Protocol) handleGetBlocks(data []byte, stream network.Stream) {
-	var req struct {
-		Start uint64 `json:"start"`
-		End   uint64 `json:"end"`
-	}
-	
-	if err := json.Unmarshal(data, &req); err != nil {
+	start, end, err := decodeBlockRange(data)
+	if err != nil {
 		p.logger.Error("Failed to unmarshal get blocks request", zap.Error(err))
 		return
 	}
 	
 	p.logger.Debug("Received get blocks request",
-		zap.Uint64("start", req.Start),
-		zap.Uint64("end", req.End),
+		zap.Uint64("start", start),
+		zap.Uint64("end", end),
 	)
 	
 	// Get blocks from blockchain
 	blocks := make([]*core.Block, 0)
-	for i := req.Start; i <= req.End && i <= req.Start+100; i++ {
+	for i := start; i <= end && i <= start+100; i++ {
 		block, err := p.blockchain.GetBlockByNumber(i)
 		if err != nil {
 			break
@@ -135,13 +151,12 @@ func (p *Protocol) handleGetBlocks(data []byte, stream network.Stream) {
 	}
 	
 	// Send blocks
-	encoder := json.NewEncoder(stream)
 	for _, block := range blocks {
 		msg := Message{
 			Type: MsgTypeBlock,
 			Data: mustMarshal(block),
 		}
-		if err := encoder.Encode(msg); err != nil {
+		if err := writeMessage(stream, &msg); err != nil {
 			p.logger.Error("Failed to send block", zap.Error(err))
 			break
 		}
@@ -154,6 +169,85 @@ func (p *Protocol) handleGetBlockHeaders(data []byte, stream network.Stream) {
 	p.logger.Debug("Received get block headers request")
 }
 
+// handlePrecommit handles incoming precommit messages. A new precommit is
+// relayed, and so is the certificate if it completed one.
+func (p *Protocol) handlePrecommit(data []byte, stream network.Stream) {
+	var precommit core.Precommit
+	if err := precommit.UnmarshalBinary(data); err != nil {
+		p.logger.Error("Failed to unmarshal precommit", zap.Error(err))
+		return
+	}
+	
+	cert, err := p.blockchain.AddPrecommit(&precommit)
+	if err != nil {
+		p.logger.Debug("Ignored precommit", zap.Uint64("height", precommit.Height), zap.Error(err))
+		return
+	}
+	
+	p.BroadcastPrecommit(&precommit)
+	if cert != nil {
+		p.logger.Info("Finalized block", zap.Uint64("number", cert.Height))
+		p.broadcastFinalityCertificate(cert)
+	}
+}
+
+// handleFinalityCertificate handles incoming finality certificates
+func (p *Protocol) handleFinalityCertificate(data []byte, stream network.Stream) {
+	var cert core.FinalityCertificate
+	if err := cert.UnmarshalBinary(data); err != nil {
+		p.logger.Error("Failed to unmarshal finality certificate", zap.Error(err))
+		return
+	}
+	
+	if err := p.blockchain.AddFinalityCertificate(&cert); err != nil {
+		p.logger.Debug("Ignored finality certificate", zap.Uint64("height", cert.Height), zap.Error(err))
+		return
+	}
+	
+	p.logger.Info("Finalized block", zap.Uint64("number", cert.Height))
+	p.broadcastFinalityCertificate(&cert)
+}
+
+// handleEvidence handles incoming double-sign evidence
+func (p *Protocol) handleEvidence(data []byte, stream network.Stream) {
+	var evidence core.Evidence
+	if err := evidence.UnmarshalBinary(data); err != nil {
+		p.logger.Error("Failed to unmarshal evidence", zap.Error(err))
+		return
+	}
+	
+	if err := p.blockchain.AddEvidence(&evidence); err != nil {
+		p.logger.Debug("Ignored evidence", zap.Uint64("height", evidence.Height()), zap.Error(err))
+		return
+	}
+	
+	p.logger.Warn("Received double-sign evidence",
+		zap.String("validator", evidence.Validator().Hex()),
+		zap.Uint64("height", evidence.Height()),
+	)
+	p.BroadcastEvidence(&evidence)
+}
+
+// PrecommitBlock signs a precommit for a block with a validator key, adds it
+// locally and gossips it
+func (p *Protocol) PrecommitBlock(block *core.Block, key *ecdsa.PrivateKey) error {
+	precommit := core.NewPrecommit(block, crypto.PublicKeyToAddress(&key.PublicKey))
+	if err := precommit.SignWithKey(key, p.blockchain.ChainID()); err != nil {
+		return err
+	}
+	
+	cert, err := p.blockchain.AddPrecommit(precommit)
+	if err != nil {
+		return err
+	}
+	
+	p.BroadcastPrecommit(precommit)
+	if cert != nil {
+		p.broadcastFinalityCertificate(cert)
+	}
+	return nil
+}
+
 // broadcastBlock broadcasts a block to all peers
 func (p *Protocol) broadcastBlock(block *core.Block) {
 	msg := Message{
@@ -161,7 +255,7 @@ func (p *Protocol) broadcastBlock(block *core.Block) {
 		Data: mustMarshal(block),
 	}
 	
-	msgData := mustMarshal(msg)
+	msgData := encodeMessage(&msg)
 	if err := p.network.Broadcast("blocks", msgData); err != nil {
 		p.logger.Error("Failed to broadcast block", zap.Error(err))
 	}
@@ -174,15 +268,104 @@ func (p *Protocol) BroadcastTransaction(tx *core.Transaction) {
 		Data: mustMarshal(tx),
 	}
 	
-	msgData := mustMarshal(msg)
+	msgData := encodeMessage(&msg)
 	if err := p.network.Broadcast("transactions", msgData); err != nil {
 		p.logger.Error("Failed to broadcast transaction", zap.Error(err))
 	}
 }
 
-// mustMarshal marshals data or panics
-func mustMarshal(v interface{}) []byte {
-	data, err := json.Marshal(v)
+// BroadcastPrecommit broadcasts a precommit to all peers
+func (p *Protocol) BroadcastPrecommit(precommit *core.Precommit) {
+	msg := Message{
+		Type: MsgTypePrecommit,
+		Data: mustMarshal(precommit),
+	}
+	
+	msgData := encodeMessage(&msg)
+	if err := p.network.Broadcast("precommits", msgData); err != nil {
+		p.logger.Error("Failed to broadcast precommit", zap.Error(err))
+	}
+}
+
+// BroadcastEvidence broadcasts double-sign evidence to all peers
+func (p *Protocol) BroadcastEvidence(evidence *core.Evidence) {
+	msg := Message{
+		Type: MsgTypeEvidence,
+		Data: mustMarshal(evidence),
+	}
+	
+	msgData := encodeMessage(&msg)
+	if err := p.network.Broadcast("evidence", msgData); err != nil {
+		p.logger.Error("Failed to broadcast evidence", zap.Error(err))
+	}
+}
+
+// broadcastFinalityCertificate broadcasts a finality certificate to all peers
+func (p *Protocol) broadcastFinalityCertificate(cert *core.FinalityCertificate) {
+	msg := Message{
+		Type: MsgTypeFinalityCertificate,
+		Data: mustMarshal(cert),
+	}
+	
+	msgData := encodeMessage(&msg)
+	if err := p.network.Broadcast("finality", msgData); err != nil {
+		p.logger.Error("Failed to broadcast finality certificate", zap.Error(err))
+	}
+}
+
+// encodeMessage frames a message for the wire
+func encodeMessage(msg *Message) []byte {
+	data := make([]byte, 5+len(msg.Data))
+	data[0] = byte(msg.Type)
+	binary.BigEndian.PutUint32(data[1:5], uint32(len(msg.Data)))
+	copy(data[5:], msg.Data)
+	return data
+}
+
+// writeMessage writes a framed message to w
+func writeMessage(w io.Writer, msg *Message) error {
+	_, err := w.Write(encodeMessage(msg))
+	return err
+}
+
+// readMessage reads a framed message from r
+func readMessage(r io.Reader) (*Message, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	
+	size := binary.BigEndian.Uint32(prefix[1:5])
+	if size > maxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+	
+	msg := &Message{Type: MessageType(prefix[0]), Data: make([]byte, size)}
+	if _, err := io.ReadFull(r, msg.Data); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// encodeBlockRange encodes a block range request payload
+func encodeBlockRange(start, end uint64) []byte {
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[:8], start)
+	binary.BigEndian.PutUint64(data[8:], end)
+	return data
+}
+
+// decodeBlockRange decodes a block range request payload
+func decodeBlockRange(data []byte) (uint64, uint64, error) {
+	if len(data) != 16 {
+		return 0, 0, errors.New("invalid block range request")
+	}
+	return binary.BigEndian.Uint64(data[:8]), binary.BigEndian.Uint64(data[8:]), nil
+}
+
+// mustMarshal encodes a value in the canonical encoding or panics
+func mustMarshal(v encoding.BinaryMarshaler) []byte {
+	data, err := v.MarshalBinary()
 	if err != nil {
 		panic(err)
 	}
@@ -1,7 +1,6 @@
 package network
 
 import (
-	"encoding/json"
 	"sync"
 	"time"
 
@@ -111,21 +110,12 @@ func (s *Syncer) sync() {
 // syncRange syncs a range of blocks
 func (s *Syncer) syncRange(start, end uint64) error {
 	// Request blocks from peers
-	req := struct {
-		Start uint64 `json:"start"`
-		End   uint64 `json:"end"`
-	}{
-		Start: start,
-		End:   end,
-	}
-	
-	reqData, _ := json.Marshal(req)
 	msg := Message{
 		Type: MsgTypeGetBlocks,
-		Data: reqData,
+		Data: encodeBlockRange(start, end),
 	}
 	
-	msgData, _ := json.Marshal(msg)
+	msgData := encodeMessage(&msg)
 	
 	// Broadcast request
 	if err := s.network.Broadcast("sync", msgData); err != nil {
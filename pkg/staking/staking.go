@@ -6,46 +6,48 @@ import (
 	"time"
 
 	"github.com/apex/pkg/consensus"
+	"github.com/apex/pkg/storage"
 	"github.com/apex/pkg/types"
 )
 
-// StakingManager manages staking operations
+// StakingManager manages staking operations. Unbonding entries live in the
+// state database and are released by the blockchain's end-block hook.
 type StakingManager struct {
-	dpos              *consensus.DPoS
-	unbondingQueue    map[types.Address][]*types.UnbondingDelegation
-	minStakeAmount    *big.Int
-	unbondingPeriod   uint64
+	dpos    *consensus.DPoS
+	stateDB *storage.StateDB
 }
 
 // NewStakingManager creates a new staking manager
-func NewStakingManager(dpos *consensus.DPoS) *StakingManager {
+func NewStakingManager(dpos *consensus.DPoS, stateDB *storage.StateDB) *StakingManager {
 	return &StakingManager{
-		dpos:            dpos,
-		unbondingQueue:  make(map[types.Address][]*types.UnbondingDelegation),
-		minStakeAmount:  types.ToWei(10.0), // Minimum 10 APX to stake
-		unbondingPeriod: types.UnbondingPeriod,
+		dpos:    dpos,
+		stateDB: stateDB,
 	}
 }
 
-// Stake allows user to stake tokens to a validator
+// Stake allows user to stake tokens to a validator in the block with
+// timestamp blockTime
 func (sm *StakingManager) Stake(
 	delegator, validator types.Address,
 	amount *big.Int,
+	blockTime time.Time,
 ) error {
 	// Validate amount
-	if amount.Cmp(sm.minStakeAmount) < 0 {
+	if amount.Cmp(sm.dpos.Params().MinDelegation) < 0 {
 		return errors.New("stake amount below minimum")
 	}
 	
 	// Delegate to validator
-	return sm.dpos.Delegate(delegator, validator, amount)
+	return sm.dpos.Delegate(delegator, validator, amount, blockTime)
 }
 
-// Unstake initiates unstaking process
+// Unstake initiates unstaking process in block currentBlock with timestamp
+// blockTime
 func (sm *StakingManager) Unstake(
 	delegator, validator types.Address,
 	amount *big.Int,
 	currentBlock uint64,
+	blockTime time.Time,
 ) error {
 	// Initiate undelegation
 	err := sm.dpos.Undelegate(delegator, validator, amount)
@@ -58,65 +60,60 @@ func (sm *StakingManager) Unstake(
 		Delegator:       delegator,
 		Validator:       validator,
 		Amount:          new(big.Int).Set(amount),
-		CompletionBlock: currentBlock + sm.unbondingPeriod,
-		CreatedAt:       time.Now(),
+		CreationHeight:  currentBlock,
+		CompletionBlock: currentBlock + sm.dpos.Params().UnbondingPeriod,
+		CreatedAt:       blockTime,
 	}
 	
 	// Add to unbonding queue
-	sm.unbondingQueue[delegator] = append(sm.unbondingQueue[delegator], unbonding)
-	
-	return nil
+	return sm.stateDB.AddUnbonding(unbonding)
 }
 
-// ProcessUnbonding processes completed unbonding delegations
-func (sm *StakingManager) ProcessUnbonding(
-	currentBlock uint64,
-) ([]*types.UnbondingDelegation, error) {
-	completed := make([]*types.UnbondingDelegation, 0)
-	
-	for delegator, unbondings := range sm.unbondingQueue {
-		remaining := make([]*types.UnbondingDelegation, 0)
-		
-		for _, unbonding := range unbondings {
-			if currentBlock >= unbonding.CompletionBlock {
-				// Unbonding complete
-				completed = append(completed, unbonding)
-			} else {
-				// Still unbonding
-				remaining = append(remaining, unbonding)
-			}
-		}
-		
-		if len(remaining) > 0 {
-			sm.unbondingQueue[delegator] = remaining
-		} else {
-			delete(sm.unbondingQueue, delegator)
-		}
-	}
-	
-	return completed, nil
-}
-
-// GetUnbondingDelegations returns unbonding delegations for a delegator
+// GetUnbondingDelegations returns the pending unbonding entries of a delegator
 func (sm *StakingManager) GetUnbondingDelegations(
 	delegator types.Address,
-) []*types.UnbondingDelegation {
-	return sm.unbondingQueue[delegator]
+) ([]*types.UnbondingDelegation, error) {
+	return sm.stateDB.GetUnbondingsByDelegator(delegator)
 }
 
-// Redelegate moves stake from one validator to another
+// Redelegate moves stake from one validator to another. The move is recorded
+// until the unbonding period passes, and stake received through a maturing
+// redelegation may not be moved on.
 func (sm *StakingManager) Redelegate(
 	delegator, srcValidator, dstValidator types.Address,
 	amount *big.Int,
+	currentBlock uint64,
+	blockTime time.Time,
 ) error {
+	pending, err := sm.stateDB.GetRedelegationsByDelegator(delegator)
+	if err != nil {
+		return err
+	}
+	if err := consensus.ValidateRedelegation(srcValidator, dstValidator, pending); err != nil {
+		return err
+	}
+	
 	// Undelegate from source
-	err := sm.dpos.Undelegate(delegator, srcValidator, amount)
+	err = sm.dpos.Undelegate(delegator, srcValidator, amount)
 	if err != nil {
 		return err
 	}
 	
 	// Delegate to destination
-	return sm.dpos.Delegate(delegator, dstValidator, amount)
+	if err := sm.dpos.Delegate(delegator, dstValidator, amount, blockTime); err != nil {
+		return err
+	}
+	
+	redelegation := &types.Redelegation{
+		Delegator:       delegator,
+		ValidatorSrc:    srcValidator,
+		ValidatorDst:    dstValidator,
+		Amount:          new(big.Int).Set(amount),
+		CreationHeight:  currentBlock,
+		CompletionBlock: currentBlock + sm.dpos.Params().UnbondingPeriod,
+		CreatedAt:       blockTime,
+	}
+	return sm.stateDB.AddRedelegation(redelegation)
 }
 
 // GetStakingInfo returns staking information for a delegator
@@ -128,7 +125,10 @@ func (sm *StakingManager) GetStakingInfo(
 		return nil, err
 	}
 	
-	unbondings := sm.GetUnbondingDelegations(delegator)
+	unbondings, err := sm.GetUnbondingDelegations(delegator)
+	if err != nil {
+		return nil, err
+	}
 	
 	return map[string]interface{}{
 		"delegator":         delegator.Hex(),
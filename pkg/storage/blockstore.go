@@ -19,28 +19,85 @@ func NewBlockStore(db *Database) *BlockStore {
 	return &BlockStore{db: db}
 }
 
-// PutBlock stores a block
+// PutBlock stores a block by hash. Side-chain blocks are stored the same way;
+// only SetCanonical makes a block reachable by number.
 func (bs *BlockStore) PutBlock(block *core.Block) error {
 	// Serialize block
-	data, err := json.Marshal(block)
+	data, err := block.MarshalBinary()
 	if err != nil {
 		return err
 	}
 	
 	// Store by hash
-	hashKey := blockHashKey(block.Hash)
-	if err := bs.db.Put(hashKey, data); err != nil {
-		return err
+	return bs.db.Put(blockHashKey(block.Hash), data)
+}
+
+// ConnectOps returns the operations that store block and its receipts and
+// make it the canonical block at its height and the latest block. They are
+// written by the caller in one batch with the block's state.
+func (bs *BlockStore) ConnectOps(block *core.Block, receipts []*core.TxReceipt) ([]BatchOp, error) {
+	data, err := block.MarshalBinary()
+	if err != nil {
+		return nil, err
 	}
+	ops := []BatchOp{{Type: BatchOpPut, Key: blockHashKey(block.Hash), Value: data}}
+	ops = append(ops, canonicalOps(block)...)
 	
-	// Store by number (for quick lookups)
-	numberKey := blockNumberKey(block.Header.Number)
-	if err := bs.db.Put(numberKey, block.Hash[:]); err != nil {
-		return err
+	// Receipts are indexed by transaction hash and by block
+	for _, receipt := range receipts {
+		data, err := json.Marshal(receipt)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, BatchOp{Type: BatchOpPut, Key: receiptKey(receipt.TxHash), Value: data})
+	}
+	data, err = json.Marshal(receipts)
+	if err != nil {
+		return nil, err
+	}
+	ops = append(ops, BatchOp{Type: BatchOpPut, Key: blockReceiptsKey(block.Hash), Value: data})
+	return ops, nil
+}
+
+// DisconnectOps returns the operations that remove block, the latest block,
+// from the canonical chain together with its receipts, making its parent the
+// latest block. The block itself stays stored by hash.
+func (bs *BlockStore) DisconnectOps(block *core.Block) ([]BatchOp, error) {
+	receipts, err := bs.GetBlockReceipts(block.Hash)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Update latest block number
-	return bs.updateLatestBlockNumber(block.Header.Number)
+	ops := make([]BatchOp, 0, len(receipts)+3)
+	for _, receipt := range receipts {
+		ops = append(ops, BatchOp{Type: BatchOpDelete, Key: receiptKey(receipt.TxHash)})
+	}
+	ops = append(ops,
+		BatchOp{Type: BatchOpDelete, Key: blockReceiptsKey(block.Hash)},
+		BatchOp{Type: BatchOpDelete, Key: blockNumberKey(block.Header.Number)},
+		BatchOp{Type: BatchOpPut, Key: latestBlockNumberKey(), Value: encodeBlockNumber(block.Header.Number - 1)},
+	)
+	return ops, nil
+}
+
+// DeleteBlock removes a stored block
+func (bs *BlockStore) DeleteBlock(hash types.Hash) error {
+	return bs.db.Delete(blockHashKey(hash))
+}
+
+// SetCanonical makes block the canonical block at its height and the latest
+// block
+func (bs *BlockStore) SetCanonical(block *core.Block) error {
+	return bs.db.Batch(canonicalOps(block))
+}
+
+// canonicalOps returns the operations that make block the canonical block at
+// its height and the latest block
+func canonicalOps(block *core.Block) []BatchOp {
+	return []BatchOp{
+		{Type: BatchOpPut, Key: blockNumberKey(block.Header.Number), Value: block.Hash[:]},
+		{Type: BatchOpPut, Key: latestBlockNumberKey(), Value: encodeBlockNumber(block.Header.Number)},
+	}
 }
 
 // GetBlock retrieves a block by hash
@@ -52,7 +109,7 @@ func (bs *BlockStore) GetBlock(hash types.Hash) (*core.Block, error) {
 	}
 	
 	var block core.Block
-	if err := json.Unmarshal(data, &block); err != nil {
+	if err := block.UnmarshalBinary(data); err != nil {
 		return nil, err
 	}
 	
@@ -87,8 +144,7 @@ func (bs *BlockStore) GetLatestBlock() (*core.Block, error) {
 
 // GetLatestBlockNumber retrieves the latest block number
 func (bs *BlockStore) GetLatestBlockNumber() (uint64, error) {
-	key := []byte("latest_block_number")
-	data, err := bs.db.Get(key)
+	data, err := bs.db.Get(latestBlockNumberKey())
 	if err != nil {
 		return 0, err
 	}
@@ -96,17 +152,9 @@ func (bs *BlockStore) GetLatestBlockNumber() (uint64, error) {
 	return binary.BigEndian.Uint64(data), nil
 }
 
-// updateLatestBlockNumber updates the latest block number
-func (bs *BlockStore) updateLatestBlockNumber(number uint64) error {
-	key := []byte("latest_block_number")
-	data := make([]byte, 8)
-	binary.BigEndian.PutUint64(data, number)
-	return bs.db.Put(key, data)
-}
-
 // PutTransaction stores a transaction
 func (bs *BlockStore) PutTransaction(tx *core.Transaction) error {
-	data, err := json.Marshal(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return err
 	}
@@ -124,7 +172,7 @@ func (bs *BlockStore) GetTransaction(hash types.Hash) (*core.Transaction, error)
 	}
 	
 	var tx core.Transaction
-	if err := json.Unmarshal(data, &tx); err != nil {
+	if err := tx.UnmarshalBinary(data); err != nil {
 		return nil, err
 	}
 	
@@ -158,6 +206,21 @@ func (bs *BlockStore) GetReceipt(txHash types.Hash) (*core.TxReceipt, error) {
 	return &receipt, nil
 }
 
+// GetBlockReceipts retrieves all receipts of a block
+func (bs *BlockStore) GetBlockReceipts(blockHash types.Hash) ([]*core.TxReceipt, error) {
+	data, err := bs.db.Get(blockReceiptsKey(blockHash))
+	if err != nil {
+		return nil, err
+	}
+	
+	var receipts []*core.TxReceipt
+	if err := json.Unmarshal(data, &receipts); err != nil {
+		return nil, err
+	}
+	
+	return receipts, nil
+}
+
 // GetBlockRange retrieves blocks in a range
 func (bs *BlockStore) GetBlockRange(start, end uint64) ([]*core.Block, error) {
 	blocks := make([]*core.Block, 0, end-start+1)
@@ -173,6 +236,45 @@ func (bs *BlockStore) GetBlockRange(start, end uint64) ([]*core.Block, error) {
 	return blocks, nil
 }
 
+// PutFinalityCertificate stores a finality certificate and records its
+// height as the finalized height
+func (bs *BlockStore) PutFinalityCertificate(cert *core.FinalityCertificate) error {
+	data, err := cert.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	
+	return bs.db.Batch([]BatchOp{
+		{Type: BatchOpPut, Key: finalityCertificateKey(cert.Height), Value: data},
+		{Type: BatchOpPut, Key: finalizedHeightKey(), Value: encodeBlockNumber(cert.Height)},
+	})
+}
+
+// GetFinalityCertificate retrieves the finality certificate for a height
+func (bs *BlockStore) GetFinalityCertificate(height uint64) (*core.FinalityCertificate, error) {
+	data, err := bs.db.Get(finalityCertificateKey(height))
+	if err != nil {
+		return nil, err
+	}
+	
+	var cert core.FinalityCertificate
+	if err := cert.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	
+	return &cert, nil
+}
+
+// GetFinalizedHeight retrieves the height of the last finalized block
+func (bs *BlockStore) GetFinalizedHeight() (uint64, error) {
+	data, err := bs.db.Get(finalizedHeightKey())
+	if err != nil {
+		return 0, err
+	}
+	
+	return binary.BigEndian.Uint64(data), nil
+}
+
 // HasBlock checks if a block exists
 func (bs *BlockStore) HasBlock(hash types.Hash) bool {
 	key := blockHashKey(hash)
@@ -189,6 +291,24 @@ func blockNumberKey(number uint64) []byte {
 	return []byte(fmt.Sprintf("block:number:%d", number))
 }
 
+func latestBlockNumberKey() []byte {
+	return []byte("latest_block_number")
+}
+
+func finalityCertificateKey(height uint64) []byte {
+	return []byte(fmt.Sprintf("finality:%d", height))
+}
+
+func finalizedHeightKey() []byte {
+	return []byte("finalized_height")
+}
+
+func encodeBlockNumber(number uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, number)
+	return data
+}
+
 func txKey(hash types.Hash) []byte {
 	return []byte(fmt.Sprintf("tx:%s", hash.Hex()))
 }
@@ -196,3 +316,7 @@ func txKey(hash types.Hash) []byte {
 func receiptKey(hash types.Hash) []byte {
 	return []byte(fmt.Sprintf("receipt:%s", hash.Hex()))
 }
+
+func blockReceiptsKey(hash types.Hash) []byte {
+	return []byte(fmt.Sprintf("receipts:block:%s", hash.Hex()))
+}
@@ -4,6 +4,9 @@ import (
 	"github.com/dgraph-io/badger/v4"
 )
 
+// ErrKeyNotFound is returned when a key does not exist
+var ErrKeyNotFound = badger.ErrKeyNotFound
+
 // Database represents the main database interface
 type Database struct {
 	db *badger.DB
@@ -22,6 +25,19 @@ func NewDatabase(path string) (*Database, error) {
 	return &Database{db: db}, nil
 }
 
+// NewMemoryDatabase creates a database that lives only in memory
+func NewMemoryDatabase() (*Database, error) {
+	opts := badger.DefaultOptions("").WithInMemory(true)
+	opts.Logger = nil
+	
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &Database{db: db}, nil
+}
+
 // Close closes the database
 func (d *Database) Close() error {
 	return d.db.Close()
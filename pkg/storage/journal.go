@@ -0,0 +1,41 @@
+package storage
+
+import "github.com/apex/pkg/types"
+
+// journalEntry records the overlay value a key had before a write so the
+// write can be undone
+type journalEntry struct {
+	key     string
+	prev    []byte
+	existed bool // whether the key was already in the overlay
+}
+
+// revert restores the previous overlay value of the entry's key
+func (j journalEntry) revert(dirty map[string][]byte) {
+	if j.existed {
+		dirty[j.key] = j.prev
+	} else {
+		delete(dirty, j.key)
+	}
+}
+
+// revision marks a snapshot: the journal length and the trie at that point
+type revision struct {
+	id           int
+	journalIndex int
+	trie         *Trie
+}
+
+// blockUndo records the committed values a block overwrote and the state
+// root before it, so the block can be reverted during a reorg
+type blockUndo struct {
+	Root    types.Hash  `json:"root"`
+	Entries []undoEntry `json:"entries"`
+}
+
+// undoEntry is the committed value of a key before a block changed it
+type undoEntry struct {
+	Key     string `json:"key"`
+	Value   []byte `json:"value,omitempty"`
+	Existed bool   `json:"existed"`
+}
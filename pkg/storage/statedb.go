@@ -2,168 +2,764 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/apex/pkg/types"
 )
 
-// StateDB manages blockchain state
+// StateDB manages blockchain state. Writes are buffered in an in-memory
+// overlay and journaled so they can be reverted to a snapshot; Commit
+// flushes them to the database in a single batch.
 type StateDB struct {
-	db *Database
+	db             *Database
+	trie           *Trie
+	committed      *Trie
+	dirty          map[string][]byte // pending writes, nil means deleted
+	journal        []journalEntry
+	revisions      []revision
+	nextRevisionID int
+	mu             sync.RWMutex
 }
 
-// NewStateDB creates a new state database
-func NewStateDB(db *Database) *StateDB {
-	return &StateDB{db: db}
-}
+// NewStateDB creates a new state database, opening the state trie at the
+// last committed root
+func NewStateDB(db *Database) (*StateDB, error) {
+	var root types.Hash
+	data, err := db.Get(stateRootKey())
+	if err == nil {
+		copy(root[:], data)
+	}
 
-// GetAccount retrieves an account by address
-func (s *StateDB) GetAccount(addr types.Address) (*types.Account, error) {
-	key := accountKey(addr)
-	data, err := s.db.Get(key)
+	trie, err := NewTrie(db, root)
 	if err != nil {
 		return nil, err
 	}
-	
+
+	return &StateDB{
+		db:        db,
+		trie:      trie,
+		committed: trie.Copy(),
+		dirty:     make(map[string][]byte),
+	}, nil
+}
+
+// GetAccount retrieves an account by address
+func (s *StateDB) GetAccount(addr types.Address) (*types.Account, error) {
 	var account types.Account
-	if err := json.Unmarshal(data, &account); err != nil {
+	if err := s.getJSON(accountKey(addr), &account); err != nil {
 		return nil, err
 	}
-	
 	return &account, nil
 }
 
 // SetAccount stores an account
 func (s *StateDB) SetAccount(account *types.Account) error {
-	data, err := json.Marshal(account)
-	if err != nil {
-		return err
-	}
-	
-	key := accountKey(account.Address)
-	return s.db.Put(key, data)
+	return s.putJSON(accountKey(account.Address), account)
 }
 
 // DeleteAccount deletes an account
 func (s *StateDB) DeleteAccount(addr types.Address) error {
-	key := accountKey(addr)
-	return s.db.Delete(key)
+	return s.delete(accountKey(addr))
 }
 
 // GetValidator retrieves a validator by address
 func (s *StateDB) GetValidator(addr types.Address) (*types.Validator, error) {
-	key := validatorKey(addr)
-	data, err := s.db.Get(key)
-	if err != nil {
-		return nil, err
-	}
-	
 	var validator types.Validator
-	if err := json.Unmarshal(data, &validator); err != nil {
+	if err := s.getJSON(validatorKey(addr), &validator); err != nil {
 		return nil, err
 	}
-	
 	return &validator, nil
 }
 
 // SetValidator stores a validator
 func (s *StateDB) SetValidator(validator *types.Validator) error {
-	data, err := json.Marshal(validator)
-	if err != nil {
-		return err
-	}
-	
-	key := validatorKey(validator.Address)
-	return s.db.Put(key, data)
+	return s.putJSON(validatorKey(validator.Address), validator)
 }
 
 // DeleteValidator deletes a validator
 func (s *StateDB) DeleteValidator(addr types.Address) error {
-	key := validatorKey(addr)
-	return s.db.Delete(key)
+	return s.delete(validatorKey(addr))
 }
 
 // GetAllValidators retrieves all validators
 func (s *StateDB) GetAllValidators() ([]*types.Validator, error) {
 	validators := make([]*types.Validator, 0)
-	
-	prefix := []byte("validator:")
-	iter := s.db.Iterator(prefix)
-	defer iter.Close()
-	
-	for iter.Rewind(); iter.Valid(); iter.Next() {
-		data, err := iter.Value()
-		if err != nil {
-			continue
-		}
-		
+
+	err := s.iterate([]byte("validator:"), func(key string, data []byte) error {
 		var validator types.Validator
 		if err := json.Unmarshal(data, &validator); err != nil {
-			continue
+			return nil
 		}
-		
 		validators = append(validators, &validator)
-	}
-	
-	return validators, nil
+		return nil
+	})
+
+	return validators, err
 }
 
 // GetDelegation retrieves a delegation
 func (s *StateDB) GetDelegation(delegator, validator types.Address) (*types.Delegation, error) {
-	key := delegationKey(delegator, validator)
-	data, err := s.db.Get(key)
-	if err != nil {
-		return nil, err
-	}
-	
 	var delegation types.Delegation
-	if err := json.Unmarshal(data, &delegation); err != nil {
+	if err := s.getJSON(delegationKey(delegator, validator), &delegation); err != nil {
 		return nil, err
 	}
-	
 	return &delegation, nil
 }
 
-// SetDelegation stores a delegation
+// SetDelegation stores a delegation and indexes it under its validator
 func (s *StateDB) SetDelegation(delegation *types.Delegation) error {
-	data, err := json.Marshal(delegation)
-	if err != nil {
+	if err := s.putJSON(delegationKey(delegation.Delegator, delegation.Validator), delegation); err != nil {
 		return err
 	}
-	
-	key := delegationKey(delegation.Delegator, delegation.Validator)
-	return s.db.Put(key, data)
+	return s.put(validatorDelegationKey(delegation.Validator, delegation.Delegator), delegation.Delegator.Bytes())
 }
 
-// DeleteDelegation deletes a delegation
+// GetAllDelegations retrieves all delegations
+func (s *StateDB) GetAllDelegations() ([]*types.Delegation, error) {
+	delegations := make([]*types.Delegation, 0)
+
+	err := s.iterate([]byte("delegation:"), func(key string, data []byte) error {
+		var delegation types.Delegation
+		if err := json.Unmarshal(data, &delegation); err != nil {
+			return nil
+		}
+		delegations = append(delegations, &delegation)
+		return nil
+	})
+
+	return delegations, err
+}
+
+// GetDelegationsByDelegator retrieves the delegations of a delegator
+func (s *StateDB) GetDelegationsByDelegator(delegator types.Address) ([]*types.Delegation, error) {
+	delegations := make([]*types.Delegation, 0)
+
+	err := s.iterate([]byte(fmt.Sprintf("delegation:%s:", delegator.Hex())), func(key string, data []byte) error {
+		var delegation types.Delegation
+		if err := json.Unmarshal(data, &delegation); err != nil {
+			return nil
+		}
+		delegations = append(delegations, &delegation)
+		return nil
+	})
+
+	return delegations, err
+}
+
+// GetDelegationsByValidator retrieves the delegations to a validator, in
+// order of delegator, through the index kept by SetDelegation
+func (s *StateDB) GetDelegationsByValidator(validator types.Address) ([]*types.Delegation, error) {
+	var delegators []types.Address
+	err := s.iterate([]byte(fmt.Sprintf("valdelegation:%s:", validator.Hex())), func(key string, data []byte) error {
+		var delegator types.Address
+		copy(delegator[:], data)
+		delegators = append(delegators, delegator)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delegations := make([]*types.Delegation, 0, len(delegators))
+	for _, delegator := range delegators {
+		delegation, err := s.GetDelegation(delegator, validator)
+		if err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, delegation)
+	}
+	return delegations, nil
+}
+
+// DeleteDelegation deletes a delegation and its index entry
 func (s *StateDB) DeleteDelegation(delegator, validator types.Address) error {
-	key := delegationKey(delegator, validator)
-	return s.db.Delete(key)
+	if err := s.delete(delegationKey(delegator, validator)); err != nil {
+		return err
+	}
+	return s.delete(validatorDelegationKey(validator, delegator))
+}
+
+// AddUnbonding stores an unbonding entry. Entries of the same delegator and
+// validator completing at the same height are merged.
+func (s *StateDB) AddUnbonding(unbonding *types.UnbondingDelegation) error {
+	key := unbondingKey(unbonding.CompletionBlock, unbonding.Delegator, unbonding.Validator)
+
+	var existing types.UnbondingDelegation
+	if err := s.getJSON(key, &existing); err == nil {
+		merged := *unbonding
+		merged.Amount = new(big.Int).Add(existing.Amount, unbonding.Amount)
+		return s.putJSON(key, &merged)
+	}
+	return s.putJSON(key, unbonding)
+}
+
+// DeleteUnbonding deletes an unbonding entry
+func (s *StateDB) DeleteUnbonding(unbonding *types.UnbondingDelegation) error {
+	return s.delete(unbondingKey(unbonding.CompletionBlock, unbonding.Delegator, unbonding.Validator))
+}
+
+// GetMatureUnbondings retrieves the unbonding entries completing at or
+// before height, oldest first
+func (s *StateDB) GetMatureUnbondings(height uint64) ([]*types.UnbondingDelegation, error) {
+	unbondings := make([]*types.UnbondingDelegation, 0)
+
+	err := s.iterate([]byte("unbonding:"), func(key string, data []byte) error {
+		var unbonding types.UnbondingDelegation
+		if err := json.Unmarshal(data, &unbonding); err != nil {
+			return nil
+		}
+		// Keys are ordered by completion height
+		if unbonding.CompletionBlock > height {
+			return errStopIteration
+		}
+		unbondings = append(unbondings, &unbonding)
+		return nil
+	})
+	if err == errStopIteration {
+		err = nil
+	}
+
+	return unbondings, err
+}
+
+// GetUnbondingsByDelegator retrieves the pending unbonding entries of a
+// delegator, oldest first
+func (s *StateDB) GetUnbondingsByDelegator(delegator types.Address) ([]*types.UnbondingDelegation, error) {
+	unbondings := make([]*types.UnbondingDelegation, 0)
+
+	err := s.iterate([]byte("unbonding:"), func(key string, data []byte) error {
+		var unbonding types.UnbondingDelegation
+		if err := json.Unmarshal(data, &unbonding); err != nil {
+			return nil
+		}
+		if unbonding.Delegator == delegator {
+			unbondings = append(unbondings, &unbonding)
+		}
+		return nil
+	})
+
+	return unbondings, err
+}
+
+// GetUnbondingsByValidator retrieves the pending unbonding entries of stake
+// bonded to a validator
+func (s *StateDB) GetUnbondingsByValidator(validator types.Address) ([]*types.UnbondingDelegation, error) {
+	unbondings := make([]*types.UnbondingDelegation, 0)
+
+	err := s.iterate([]byte("unbonding:"), func(key string, data []byte) error {
+		var unbonding types.UnbondingDelegation
+		if err := json.Unmarshal(data, &unbonding); err != nil {
+			return nil
+		}
+		if unbonding.Validator == validator {
+			unbondings = append(unbondings, &unbonding)
+		}
+		return nil
+	})
+
+	return unbondings, err
+}
+
+// SetUnbonding overwrites an unbonding entry, unlike AddUnbonding which
+// merges
+func (s *StateDB) SetUnbonding(unbonding *types.UnbondingDelegation) error {
+	return s.putJSON(unbondingKey(unbonding.CompletionBlock, unbonding.Delegator, unbonding.Validator), unbonding)
+}
+
+// AddRedelegation stores a redelegation entry. Entries of the same delegator
+// and validators completing at the same height are merged.
+func (s *StateDB) AddRedelegation(redelegation *types.Redelegation) error {
+	key := redelegationKey(redelegation)
+
+	var existing types.Redelegation
+	if err := s.getJSON(key, &existing); err == nil {
+		merged := *redelegation
+		merged.Amount = new(big.Int).Add(existing.Amount, redelegation.Amount)
+		return s.putJSON(key, &merged)
+	}
+	return s.putJSON(key, redelegation)
+}
+
+// DeleteRedelegation deletes a redelegation entry
+func (s *StateDB) DeleteRedelegation(redelegation *types.Redelegation) error {
+	return s.delete(redelegationKey(redelegation))
+}
+
+// GetMatureRedelegations retrieves the redelegation entries completing at or
+// before height, oldest first
+func (s *StateDB) GetMatureRedelegations(height uint64) ([]*types.Redelegation, error) {
+	redelegations := make([]*types.Redelegation, 0)
+
+	err := s.iterate([]byte("redelegation:"), func(key string, data []byte) error {
+		var redelegation types.Redelegation
+		if err := json.Unmarshal(data, &redelegation); err != nil {
+			return nil
+		}
+		// Keys are ordered by completion height
+		if redelegation.CompletionBlock > height {
+			return errStopIteration
+		}
+		redelegations = append(redelegations, &redelegation)
+		return nil
+	})
+	if err == errStopIteration {
+		err = nil
+	}
+
+	return redelegations, err
+}
+
+// GetRedelegationsByDelegator retrieves the maturing redelegation entries of
+// a delegator, oldest first
+func (s *StateDB) GetRedelegationsByDelegator(delegator types.Address) ([]*types.Redelegation, error) {
+	return s.filterRedelegations(func(r *types.Redelegation) bool {
+		return r.Delegator == delegator
+	})
+}
+
+// GetRedelegationsFromValidator retrieves the maturing redelegation entries
+// moving stake away from a validator, oldest first
+func (s *StateDB) GetRedelegationsFromValidator(validator types.Address) ([]*types.Redelegation, error) {
+	return s.filterRedelegations(func(r *types.Redelegation) bool {
+		return r.ValidatorSrc == validator
+	})
+}
+
+// filterRedelegations retrieves the redelegation entries matching keep
+func (s *StateDB) filterRedelegations(keep func(*types.Redelegation) bool) ([]*types.Redelegation, error) {
+	redelegations := make([]*types.Redelegation, 0)
+
+	err := s.iterate([]byte("redelegation:"), func(key string, data []byte) error {
+		var redelegation types.Redelegation
+		if err := json.Unmarshal(data, &redelegation); err != nil {
+			return nil
+		}
+		if keep(&redelegation) {
+			redelegations = append(redelegations, &redelegation)
+		}
+		return nil
+	})
+
+	return redelegations, err
+}
+
+// GetVote retrieves a voter's vote for a validator
+func (s *StateDB) GetVote(voter, validator types.Address) (*types.Vote, error) {
+	var vote types.Vote
+	if err := s.getJSON(voteKey(voter, validator), &vote); err != nil {
+		return nil, err
+	}
+	return &vote, nil
+}
+
+// SetVote stores a vote
+func (s *StateDB) SetVote(vote *types.Vote) error {
+	return s.putJSON(voteKey(vote.Voter, vote.Validator), vote)
+}
+
+// DeleteVote deletes a vote
+func (s *StateDB) DeleteVote(voter, validator types.Address) error {
+	return s.delete(voteKey(voter, validator))
+}
+
+// GetVotesByVoter retrieves all votes cast by a voter
+func (s *StateDB) GetVotesByVoter(voter types.Address) ([]*types.Vote, error) {
+	votes := make([]*types.Vote, 0)
+
+	err := s.iterate([]byte(fmt.Sprintf("vote:%s:", voter.Hex())), func(key string, data []byte) error {
+		var vote types.Vote
+		if err := json.Unmarshal(data, &vote); err != nil {
+			return nil
+		}
+		votes = append(votes, &vote)
+		return nil
+	})
+
+	return votes, err
+}
+
+// GetValidatorSet retrieves the validator set of an epoch
+func (s *StateDB) GetValidatorSet(epoch uint64) (*types.ValidatorSet, error) {
+	var set types.ValidatorSet
+	if err := s.getJSON(validatorSetKey(epoch), &set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// SetValidatorSet stores the validator set of an epoch. Sets are kept so
+// that any past block can be checked against the set active at its height.
+func (s *StateDB) SetValidatorSet(set *types.ValidatorSet) error {
+	return s.putJSON(validatorSetKey(set.Epoch), set)
+}
+
+// AddSlashingEvent stores a slashing event after the validator's earlier
+// events, including those applied by the same block
+func (s *StateDB) AddSlashingEvent(event *types.SlashingEvent) error {
+	for seq := uint32(0); ; seq++ {
+		key := slashingEventKey(event, seq)
+		if _, err := s.get(key); err == ErrKeyNotFound {
+			return s.putJSON(key, event)
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// GetSlashingEvents retrieves the slashing events of a validator, oldest
+// first
+func (s *StateDB) GetSlashingEvents(validator types.Address) ([]*types.SlashingEvent, error) {
+	return s.slashingEvents([]byte(fmt.Sprintf("slashing:%s:", validator.Hex())))
+}
+
+// GetAllSlashingEvents retrieves the slashing events of all validators
+func (s *StateDB) GetAllSlashingEvents() ([]*types.SlashingEvent, error) {
+	return s.slashingEvents([]byte("slashing:"))
+}
+
+func (s *StateDB) slashingEvents(prefix []byte) ([]*types.SlashingEvent, error) {
+	events := make([]*types.SlashingEvent, 0)
+
+	err := s.iterate(prefix, func(key string, data []byte) error {
+		var event types.SlashingEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil
+		}
+		events = append(events, &event)
+		return nil
+	})
+
+	return events, err
+}
+
+// GetSigningInfo retrieves the signing window of a validator
+func (s *StateDB) GetSigningInfo(addr types.Address) (*types.SigningInfo, error) {
+	var info types.SigningInfo
+	if err := s.getJSON(signingInfoKey(addr), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// SetSigningInfo stores the signing window of a validator
+func (s *StateDB) SetSigningInfo(info *types.SigningInfo) error {
+	return s.putJSON(signingInfoKey(info.Address), info)
+}
+
+// SetCommittedEvidence records that double-sign evidence against a
+// validator at a height was committed, so the same offence is not punished
+// twice
+func (s *StateDB) SetCommittedEvidence(validator types.Address, height uint64, hash types.Hash) error {
+	return s.put(evidenceKey(validator, height), hash[:])
+}
+
+// HasCommittedEvidence reports whether double-sign evidence against a
+// validator at a height was committed
+func (s *StateDB) HasCommittedEvidence(validator types.Address, height uint64) (bool, error) {
+	_, err := s.get(evidenceKey(validator, height))
+	if err == ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
 }
 
-// GetStateRoot computes the state root hash
+// GetParams retrieves the chain parameters
+func (s *StateDB) GetParams() (*types.Params, error) {
+	var params types.Params
+	if err := s.getJSON(paramsKey(), &params); err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+// SetParams stores the chain parameters
+func (s *StateDB) SetParams(params *types.Params) error {
+	return s.putJSON(paramsKey(), params)
+}
+
+// GetStateRoot returns the state root, including uncommitted changes
 func (s *StateDB) GetStateRoot() (types.Hash, error) {
-	// Simplified implementation - in production, use Merkle Patricia Tree
-	var root types.Hash
-	// Compute root from all state...
-	return root, nil
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.trie.Hash(), nil
 }
 
-// Commit commits state changes
+// Commit atomically writes all pending changes, the state trie nodes and the
+// new state root to the database
 func (s *StateDB) Commit() error {
-	// In a full implementation, this would batch and commit all pending changes
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(nil, nil)
+}
+
+// CommitBlock commits like Commit and also records what the changes
+// overwrote, so the block can later be undone with RevertBlock. The extra
+// operations, such as those storing the block itself, are written in the
+// same batch, so either all of them are persisted or none.
+func (s *StateDB) CommitBlock(blockHash types.Hash, extra ...BatchOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(&blockHash, extra)
+}
+
+// RevertBlock undoes the state changes committed by CommitBlock for the given
+// block, writing the extra operations in the same batch. Blocks must be
+// reverted newest first; pending changes are dropped.
+func (s *StateDB) RevertBlock(blockHash types.Hash, extra ...BatchOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := s.db.Get(stateUndoKey(blockHash))
+	if err != nil {
+		return fmt.Errorf("no state undo record for block %s: %w", blockHash.Hex(), err)
+	}
+	var undo blockUndo
+	if err := json.Unmarshal(data, &undo); err != nil {
+		return err
+	}
+
+	trie, err := NewTrie(s.db, undo.Root)
+	if err != nil {
+		return err
+	}
+
+	ops := make([]BatchOp, 0, len(undo.Entries)+2)
+	for _, entry := range undo.Entries {
+		if entry.Existed {
+			ops = append(ops, BatchOp{Type: BatchOpPut, Key: []byte(entry.Key), Value: entry.Value})
+		} else {
+			ops = append(ops, BatchOp{Type: BatchOpDelete, Key: []byte(entry.Key)})
+		}
+	}
+	ops = append(ops,
+		BatchOp{Type: BatchOpPut, Key: stateRootKey(), Value: undo.Root[:]},
+		BatchOp{Type: BatchOpDelete, Key: stateUndoKey(blockHash)},
+	)
+	ops = append(ops, extra...)
+
+	if err := s.db.Batch(ops); err != nil {
+		return err
+	}
+
+	s.trie = trie
+	s.committed = trie.Copy()
+	s.resetOverlay()
+	return nil
+}
+
+// commit flushes the overlay and the extra operations in one batch. When
+// blockHash is set, an undo record for the block is written in the same
+// batch. The caller must hold s.mu.
+func (s *StateDB) commit(blockHash *types.Hash, extra []BatchOp) error {
+	keys := make([]string, 0, len(s.dirty))
+	for key := range s.dirty {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ops := make([]BatchOp, 0, len(keys))
+	for _, key := range keys {
+		if value := s.dirty[key]; value != nil {
+			ops = append(ops, BatchOp{Type: BatchOpPut, Key: []byte(key), Value: value})
+		} else {
+			ops = append(ops, BatchOp{Type: BatchOpDelete, Key: []byte(key)})
+		}
+	}
+
+	if blockHash != nil {
+		undo := blockUndo{Root: s.committed.Hash(), Entries: make([]undoEntry, 0, len(keys))}
+		for _, key := range keys {
+			prev, err := s.db.Get([]byte(key))
+			if err != nil && err != ErrKeyNotFound {
+				return err
+			}
+			undo.Entries = append(undo.Entries, undoEntry{Key: key, Value: prev, Existed: err == nil})
+		}
+		data, err := json.Marshal(undo)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, BatchOp{Type: BatchOpPut, Key: stateUndoKey(*blockHash), Value: data})
+	}
+
+	root, trieOps := s.trie.Commit()
+	ops = append(ops, trieOps...)
+	ops = append(ops, BatchOp{Type: BatchOpPut, Key: stateRootKey(), Value: root[:]})
+	ops = append(ops, extra...)
+
+	if err := s.db.Batch(ops); err != nil {
+		return err
+	}
+
+	s.committed = s.trie.Copy()
+	s.resetOverlay()
 	return nil
 }
 
-// Snapshot creates a state snapshot
+// Discard drops all changes made since the last commit
+func (s *StateDB) Discard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.trie = s.committed.Copy()
+	s.resetOverlay()
+}
+
+// Snapshot creates a state snapshot and returns its ID
 func (s *StateDB) Snapshot() int {
-	// Returns snapshot ID - not fully implemented
-	return 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextRevisionID
+	s.nextRevisionID++
+	s.revisions = append(s.revisions, revision{
+		id:           id,
+		journalIndex: len(s.journal),
+		trie:         s.trie.Copy(),
+	})
+	return id
 }
 
-// RevertToSnapshot reverts to a snapshot
+// RevertToSnapshot reverts all changes made since the given snapshot was
+// taken. Snapshots taken after it are invalidated; unknown IDs are ignored.
 func (s *StateDB) RevertToSnapshot(snapshot int) {
-	// Revert logic - not fully implemented
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := sort.Search(len(s.revisions), func(i int) bool {
+		return s.revisions[i].id >= snapshot
+	})
+	if idx == len(s.revisions) || s.revisions[idx].id != snapshot {
+		return
+	}
+	rev := s.revisions[idx]
+
+	for i := len(s.journal) - 1; i >= rev.journalIndex; i-- {
+		s.journal[i].revert(s.dirty)
+	}
+	s.journal = s.journal[:rev.journalIndex]
+	s.trie = rev.trie
+	s.revisions = s.revisions[:idx]
+}
+
+// getJSON reads and decodes a state entry
+func (s *StateDB) getJSON(key []byte, v interface{}) error {
+	data, err := s.get(key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// putJSON encodes and writes a state entry
+func (s *StateDB) putJSON(key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.put(key, data)
+}
+
+// get reads a state entry through the overlay
+func (s *StateDB) get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if value, ok := s.dirty[string(key)]; ok {
+		if value == nil {
+			return nil, ErrKeyNotFound
+		}
+		return value, nil
+	}
+	return s.db.Get(key)
+}
+
+// put buffers a state entry and updates the trie
+func (s *StateDB) put(key, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.trie.Update(key, data); err != nil {
+		return err
+	}
+	s.write(string(key), append([]byte(nil), data...))
+	return nil
+}
+
+// delete buffers the removal of a state entry and updates the trie
+func (s *StateDB) delete(key []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.trie.Delete(key); err != nil {
+		return err
+	}
+	s.write(string(key), nil)
+	return nil
+}
+
+// write records the previous overlay value in the journal and sets the new one
+func (s *StateDB) write(key string, value []byte) {
+	prev, existed := s.dirty[key]
+	s.journal = append(s.journal, journalEntry{key: key, prev: prev, existed: existed})
+	s.dirty[key] = value
+}
+
+// iterate calls fn for every live entry under prefix, merging the database
+// with the overlay, in key order
+func (s *StateDB) iterate(prefix []byte, fn func(key string, value []byte) error) error {
+	s.mu.RLock()
+	entries := make(map[string][]byte)
+
+	iter := s.db.Iterator(prefix)
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		value, err := iter.Value()
+		if err != nil {
+			continue
+		}
+		entries[string(iter.Key())] = value
+	}
+	iter.Close()
+
+	for key, value := range s.dirty {
+		if strings.HasPrefix(key, string(prefix)) {
+			entries[key] = value
+		}
+	}
+	s.mu.RUnlock()
+
+	keys := make([]string, 0, len(entries))
+	for key, value := range entries {
+		if value != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := fn(key, entries[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errStopIteration ends an iteration early without error
+var errStopIteration = errors.New("stop iteration")
+
+// resetOverlay clears pending writes, the journal and all snapshots
+func (s *StateDB) resetOverlay() {
+	s.dirty = make(map[string][]byte)
+	s.journal = s.journal[:0]
+	s.revisions = s.revisions[:0]
 }
 
 // Key generation helpers
@@ -178,3 +774,55 @@ func validatorKey(addr types.Address) []byte {
 func delegationKey(delegator, validator types.Address) []byte {
 	return []byte(fmt.Sprintf("delegation:%s:%s", delegator.Hex(), validator.Hex()))
 }
+
+// validatorDelegationKey indexes a delegation by its validator; the entry
+// holds the delegator's address
+func validatorDelegationKey(validator, delegator types.Address) []byte {
+	return []byte(fmt.Sprintf("valdelegation:%s:%s", validator.Hex(), delegator.Hex()))
+}
+
+// unbondingKey orders entries by completion height; the height is zero
+// padded so that key order matches numeric order
+func unbondingKey(completionBlock uint64, delegator, validator types.Address) []byte {
+	return []byte(fmt.Sprintf("unbonding:%020d:%s:%s", completionBlock, delegator.Hex(), validator.Hex()))
+}
+
+// redelegationKey orders entries by completion height like unbondingKey
+func redelegationKey(r *types.Redelegation) []byte {
+	return []byte(fmt.Sprintf("redelegation:%020d:%s:%s:%s",
+		r.CompletionBlock, r.Delegator.Hex(), r.ValidatorSrc.Hex(), r.ValidatorDst.Hex()))
+}
+
+func voteKey(voter, validator types.Address) []byte {
+	return []byte(fmt.Sprintf("vote:%s:%s", voter.Hex(), validator.Hex()))
+}
+
+func validatorSetKey(epoch uint64) []byte {
+	return []byte(fmt.Sprintf("valset:%020d", epoch))
+}
+
+// slashingEventKey orders a validator's events by the height that applied
+// them, like unbondingKey, and then by seq, their order within the block
+func slashingEventKey(event *types.SlashingEvent, seq uint32) []byte {
+	return []byte(fmt.Sprintf("slashing:%s:%020d:%010d", event.Validator.Hex(), event.Height, seq))
+}
+
+func signingInfoKey(addr types.Address) []byte {
+	return []byte(fmt.Sprintf("signinfo:%s", addr.Hex()))
+}
+
+func evidenceKey(validator types.Address, height uint64) []byte {
+	return []byte(fmt.Sprintf("evidence:%s:%020d", validator.Hex(), height))
+}
+
+func paramsKey() []byte {
+	return []byte("params")
+}
+
+func stateRootKey() []byte {
+	return []byte("state:root")
+}
+
+func stateUndoKey(blockHash types.Hash) []byte {
+	return []byte(fmt.Sprintf("state:undo:%s", blockHash.Hex()))
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/apex/pkg/types"
+)
+
+func TestSlashingEventsOfOneBlockAreKept(t *testing.T) {
+	db, err := NewMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	state, err := NewStateDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator := types.Address{1}
+	for i, infraction := range []uint64{7, 9} {
+		event := &types.SlashingEvent{
+			Validator:        validator,
+			Reason:           "double_sign",
+			Fraction:         500,
+			Amount:           big.NewInt(int64(100 * (i + 1))),
+			InfractionHeight: infraction,
+			Height:           12,
+		}
+		if err := state.AddSlashingEvent(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := state.CommitBlock(types.Hash{12}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := state.GetSlashingEvents(validator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].InfractionHeight != 7 || events[1].InfractionHeight != 9 {
+		t.Fatalf("got %d events, want both in the order they were added", len(events))
+	}
+}
+
+func TestDelegationsIndexedByValidator(t *testing.T) {
+	db, err := NewMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	state, err := NewStateDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator := types.Address{1}
+	for _, delegation := range []*types.Delegation{
+		types.NewDelegation(types.Address{3}, validator, big.NewInt(30)),
+		types.NewDelegation(types.Address{2}, validator, big.NewInt(20)),
+		types.NewDelegation(types.Address{2}, types.Address{9}, big.NewInt(90)),
+	} {
+		if err := state.SetDelegation(delegation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := state.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.DeleteDelegation(types.Address{3}, validator); err != nil {
+		t.Fatal(err)
+	}
+
+	delegations, err := state.GetDelegationsByValidator(validator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delegations) != 1 || delegations[0].Delegator != (types.Address{2}) || delegations[0].Amount.Int64() != 20 {
+		t.Fatalf("got %d delegations, want only the remaining one to the validator", len(delegations))
+	}
+}
+
+func TestNestedSnapshotsRevert(t *testing.T) {
+	db, err := NewMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	state, err := NewStateDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := types.Address{1}, types.Address{2}
+	setBalance := func(addr types.Address, balance int64) {
+		t.Helper()
+		account := types.NewAccount(addr)
+		account.Balance = big.NewInt(balance)
+		if err := state.SetAccount(account); err != nil {
+			t.Fatal(err)
+		}
+	}
+	balanceOf := func(addr types.Address) int64 {
+		t.Helper()
+		account, err := state.GetAccount(addr)
+		if err == ErrKeyNotFound {
+			return -1
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return account.Balance.Int64()
+	}
+	root := func() types.Hash {
+		t.Helper()
+		hash, err := state.GetStateRoot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	setBalance(a, 1)
+	outer, outerRoot := state.Snapshot(), root()
+	setBalance(a, 2)
+	inner, innerRoot := state.Snapshot(), root()
+	setBalance(a, 3)
+	setBalance(b, 4)
+
+	state.RevertToSnapshot(inner)
+	if balanceOf(a) != 2 || balanceOf(b) != -1 || root() != innerRoot {
+		t.Fatalf("after inner revert a=%d b=%d, want 2 and absent at the inner root", balanceOf(a), balanceOf(b))
+	}
+
+	state.RevertToSnapshot(outer)
+	if balanceOf(a) != 1 || root() != outerRoot {
+		t.Fatalf("after outer revert a=%d, want 1 at the outer root", balanceOf(a))
+	}
+
+	// The inner snapshot was taken after the outer one and is gone with it
+	setBalance(a, 5)
+	state.RevertToSnapshot(inner)
+	if balanceOf(a) != 5 {
+		t.Fatalf("revert to an invalidated snapshot changed a to %d", balanceOf(a))
+	}
+}
@@ -0,0 +1,535 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apex/pkg/types"
+)
+
+// Trie is a Merkle Patricia trie whose nodes are persisted in the database.
+// Keys are hashed before insertion so the trie stays balanced regardless of
+// the key layout used by callers. The empty trie has the zero root hash.
+type Trie struct {
+	db   *Database
+	root trieNode
+}
+
+// trieNode is one of *shortNode, *fullNode, hashNode or valueNode
+type trieNode interface{}
+
+// shortNode is a leaf (Val is a valueNode) or an extension (Val is a branch)
+type shortNode struct {
+	Key   []byte // nibbles, terminated by 16 for leaves
+	Val   trieNode
+	flags nodeFlag
+}
+
+// fullNode is a branch with one child per nibble plus a value slot
+type fullNode struct {
+	Children [17]trieNode
+	flags    nodeFlag
+}
+
+// hashNode is a reference to a node stored in the database
+type hashNode []byte
+
+// valueNode holds the raw value stored under a key
+type valueNode []byte
+
+// nodeFlag caches the hash of a node and tracks whether it must be written
+type nodeFlag struct {
+	hash  hashNode
+	dirty bool
+}
+
+// Node encoding tags
+const (
+	nodeTagShort byte = 0x01
+	nodeTagFull  byte = 0x02
+
+	childEmpty byte = 0x00
+	childHash  byte = 0x01
+	childValue byte = 0x02
+)
+
+var errTrieNodeNotFound = errors.New("trie node not found")
+
+// NewTrie opens a trie at the given root. A zero root opens an empty trie.
+func NewTrie(db *Database, root types.Hash) (*Trie, error) {
+	t := &Trie{db: db}
+	if root != (types.Hash{}) {
+		n, err := t.resolve(hashNode(root[:]))
+		if err != nil {
+			return nil, err
+		}
+		t.root = n
+	}
+	return t, nil
+}
+
+// Copy returns an independent trie sharing the same (immutable) nodes
+func (t *Trie) Copy() *Trie {
+	return &Trie{db: t.db, root: t.root}
+}
+
+// Get returns the value stored under key, or nil if it is absent
+func (t *Trie) Get(key []byte) ([]byte, error) {
+	value, newRoot, resolved, err := t.get(t.root, keyToNibbles(trieKey(key)), 0)
+	if err == nil && resolved {
+		t.root = newRoot
+	}
+	return value, err
+}
+
+// Update stores value under key. An empty value removes the key.
+func (t *Trie) Update(key, value []byte) error {
+	k := keyToNibbles(trieKey(key))
+	if len(value) == 0 {
+		_, n, err := t.delete(t.root, k)
+		if err != nil {
+			return err
+		}
+		t.root = n
+		return nil
+	}
+	_, n, err := t.insert(t.root, k, valueNode(append([]byte(nil), value...)))
+	if err != nil {
+		return err
+	}
+	t.root = n
+	return nil
+}
+
+// Delete removes key from the trie
+func (t *Trie) Delete(key []byte) error {
+	return t.Update(key, nil)
+}
+
+// Hash returns the root hash of the trie
+func (t *Trie) Hash() types.Hash {
+	var root types.Hash
+	if t.root == nil {
+		return root
+	}
+	copy(root[:], t.hashNode(t.root))
+	return root
+}
+
+// Commit returns the root hash and the batch operations that persist every
+// node modified since the last commit
+func (t *Trie) Commit() (types.Hash, []BatchOp) {
+	root := t.Hash()
+	ops := make([]BatchOp, 0)
+	if t.root != nil {
+		t.commit(t.root, &ops)
+	}
+	return root, ops
+}
+
+// get walks the trie; it also reports whether hash references were resolved
+// so the caller can keep the expanded nodes in memory
+func (t *Trie) get(n trieNode, key []byte, pos int) ([]byte, trieNode, bool, error) {
+	switch n := n.(type) {
+	case nil:
+		return nil, nil, false, nil
+	case valueNode:
+		return n, n, false, nil
+	case *shortNode:
+		if len(key)-pos < len(n.Key) || !bytes.Equal(n.Key, key[pos:pos+len(n.Key)]) {
+			return nil, n, false, nil
+		}
+		value, child, resolved, err := t.get(n.Val, key, pos+len(n.Key))
+		if err == nil && resolved {
+			n = n.copy()
+			n.Val = child
+		}
+		return value, n, resolved, err
+	case *fullNode:
+		value, child, resolved, err := t.get(n.Children[key[pos]], key, pos+1)
+		if err == nil && resolved {
+			n = n.copy()
+			n.Children[key[pos]] = child
+		}
+		return value, n, resolved, err
+	case hashNode:
+		child, err := t.resolve(n)
+		if err != nil {
+			return nil, n, false, err
+		}
+		value, newNode, _, err := t.get(child, key, pos)
+		return value, newNode, true, err
+	default:
+		return nil, nil, false, fmt.Errorf("invalid trie node type %T", n)
+	}
+}
+
+// insert adds value under key below n and returns the replacement node
+func (t *Trie) insert(n trieNode, key []byte, value trieNode) (bool, trieNode, error) {
+	if len(key) == 0 {
+		if v, ok := n.(valueNode); ok {
+			if nv, ok := value.(valueNode); ok {
+				return !bytes.Equal(v, nv), value, nil
+			}
+		}
+		return true, value, nil
+	}
+
+	switch n := n.(type) {
+	case *shortNode:
+		matchlen := prefixLen(key, n.Key)
+		// The whole key of the node matches: continue below it
+		if matchlen == len(n.Key) {
+			dirty, nn, err := t.insert(n.Val, key[matchlen:], value)
+			if !dirty || err != nil {
+				return false, n, err
+			}
+			return true, &shortNode{Key: n.Key, Val: nn, flags: newNodeFlag()}, nil
+		}
+		// Otherwise branch out at the first differing nibble
+		branch := &fullNode{flags: newNodeFlag()}
+		var err error
+		_, branch.Children[n.Key[matchlen]], err = t.insert(nil, n.Key[matchlen+1:], n.Val)
+		if err != nil {
+			return false, nil, err
+		}
+		_, branch.Children[key[matchlen]], err = t.insert(nil, key[matchlen+1:], value)
+		if err != nil {
+			return false, nil, err
+		}
+		if matchlen == 0 {
+			return true, branch, nil
+		}
+		return true, &shortNode{Key: key[:matchlen], Val: branch, flags: newNodeFlag()}, nil
+
+	case *fullNode:
+		dirty, nn, err := t.insert(n.Children[key[0]], key[1:], value)
+		if !dirty || err != nil {
+			return false, n, err
+		}
+		n = n.copy()
+		n.flags = newNodeFlag()
+		n.Children[key[0]] = nn
+		return true, n, nil
+
+	case nil:
+		return true, &shortNode{Key: key, Val: value, flags: newNodeFlag()}, nil
+
+	case hashNode:
+		rn, err := t.resolve(n)
+		if err != nil {
+			return false, nil, err
+		}
+		dirty, nn, err := t.insert(rn, key, value)
+		if !dirty || err != nil {
+			return false, rn, err
+		}
+		return true, nn, nil
+
+	default:
+		return false, nil, fmt.Errorf("invalid trie node type %T", n)
+	}
+}
+
+// delete removes key below n and returns the replacement node, collapsing
+// branches that are left with a single child
+func (t *Trie) delete(n trieNode, key []byte) (bool, trieNode, error) {
+	switch n := n.(type) {
+	case *shortNode:
+		matchlen := prefixLen(key, n.Key)
+		if matchlen < len(n.Key) {
+			return false, n, nil // key not present
+		}
+		if matchlen == len(key) {
+			return true, nil, nil // remove the whole leaf
+		}
+		dirty, child, err := t.delete(n.Val, key[len(n.Key):])
+		if !dirty || err != nil {
+			return false, n, err
+		}
+		switch child := child.(type) {
+		case *shortNode:
+			// Merge the two short nodes into one
+			merged := append(append([]byte(nil), n.Key...), child.Key...)
+			return true, &shortNode{Key: merged, Val: child.Val, flags: newNodeFlag()}, nil
+		default:
+			return true, &shortNode{Key: n.Key, Val: child, flags: newNodeFlag()}, nil
+		}
+
+	case *fullNode:
+		dirty, nn, err := t.delete(n.Children[key[0]], key[1:])
+		if !dirty || err != nil {
+			return false, n, err
+		}
+		n = n.copy()
+		n.flags = newNodeFlag()
+		n.Children[key[0]] = nn
+		if nn != nil {
+			return true, n, nil
+		}
+
+		// Find out whether only one child is left
+		pos := -1
+		for i, child := range &n.Children {
+			if child != nil {
+				if pos == -1 {
+					pos = i
+				} else {
+					pos = -2
+					break
+				}
+			}
+		}
+		if pos >= 0 {
+			if pos != 16 {
+				child, err := t.resolve(n.Children[pos])
+				if err != nil {
+					return false, nil, err
+				}
+				if cnode, ok := child.(*shortNode); ok {
+					k := append([]byte{byte(pos)}, cnode.Key...)
+					return true, &shortNode{Key: k, Val: cnode.Val, flags: newNodeFlag()}, nil
+				}
+			}
+			return true, &shortNode{Key: []byte{byte(pos)}, Val: n.Children[pos], flags: newNodeFlag()}, nil
+		}
+		return true, n, nil
+
+	case valueNode:
+		return true, nil, nil
+
+	case nil:
+		return false, nil, nil
+
+	case hashNode:
+		rn, err := t.resolve(n)
+		if err != nil {
+			return false, nil, err
+		}
+		dirty, nn, err := t.delete(rn, key)
+		if !dirty || err != nil {
+			return false, rn, err
+		}
+		return true, nn, nil
+
+	default:
+		return false, nil, fmt.Errorf("invalid trie node type %T", n)
+	}
+}
+
+// resolve loads a node referenced by hash; other nodes are returned as is
+func (t *Trie) resolve(n trieNode) (trieNode, error) {
+	hash, ok := n.(hashNode)
+	if !ok {
+		return n, nil
+	}
+	data, err := t.db.Get(trieNodeKey(hash))
+	if err != nil {
+		return nil, errTrieNodeNotFound
+	}
+	return decodeTrieNode(hash, data)
+}
+
+// hashNode computes (and caches) the hash of n
+func (t *Trie) hashNode(n trieNode) hashNode {
+	switch n := n.(type) {
+	case hashNode:
+		return n
+	case *shortNode:
+		if n.flags.hash == nil {
+			n.flags.hash = sha256Node(t.encodeNode(n))
+		}
+		return n.flags.hash
+	case *fullNode:
+		if n.flags.hash == nil {
+			n.flags.hash = sha256Node(t.encodeNode(n))
+		}
+		return n.flags.hash
+	default:
+		return nil
+	}
+}
+
+// commit appends a put operation for every dirty node reachable from n
+func (t *Trie) commit(n trieNode, ops *[]BatchOp) {
+	switch n := n.(type) {
+	case *shortNode:
+		if !n.flags.dirty {
+			return
+		}
+		if _, ok := n.Val.(valueNode); !ok {
+			t.commit(n.Val, ops)
+		}
+		hash := t.hashNode(n)
+		*ops = append(*ops, BatchOp{Type: BatchOpPut, Key: trieNodeKey(hash), Value: t.encodeNode(n)})
+		n.flags.dirty = false
+	case *fullNode:
+		if !n.flags.dirty {
+			return
+		}
+		for i := 0; i < 16; i++ {
+			if n.Children[i] != nil {
+				t.commit(n.Children[i], ops)
+			}
+		}
+		hash := t.hashNode(n)
+		*ops = append(*ops, BatchOp{Type: BatchOpPut, Key: trieNodeKey(hash), Value: t.encodeNode(n)})
+		n.flags.dirty = false
+	}
+}
+
+// encodeNode serializes a node, referencing child nodes by hash
+func (t *Trie) encodeNode(n trieNode) []byte {
+	var buf bytes.Buffer
+	switch n := n.(type) {
+	case *shortNode:
+		buf.WriteByte(nodeTagShort)
+		writeBytes(&buf, n.Key)
+		t.encodeChild(&buf, n.Val)
+	case *fullNode:
+		buf.WriteByte(nodeTagFull)
+		for _, child := range &n.Children {
+			t.encodeChild(&buf, child)
+		}
+	}
+	return buf.Bytes()
+}
+
+// encodeChild writes a child reference into buf
+func (t *Trie) encodeChild(buf *bytes.Buffer, child trieNode) {
+	switch child := child.(type) {
+	case nil:
+		buf.WriteByte(childEmpty)
+	case valueNode:
+		buf.WriteByte(childValue)
+		writeBytes(buf, child)
+	default:
+		buf.WriteByte(childHash)
+		buf.Write(t.hashNode(child))
+	}
+}
+
+// decodeTrieNode parses a node previously written by encodeNode
+func decodeTrieNode(hash hashNode, data []byte) (trieNode, error) {
+	r := bytes.NewReader(data)
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	flags := nodeFlag{hash: append(hashNode(nil), hash...)}
+
+	switch tag {
+	case nodeTagShort:
+		key, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		val, err := decodeChild(r)
+		if err != nil {
+			return nil, err
+		}
+		return &shortNode{Key: key, Val: val, flags: flags}, nil
+	case nodeTagFull:
+		n := &fullNode{flags: flags}
+		for i := range n.Children {
+			if n.Children[i], err = decodeChild(r); err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("invalid trie node tag %d", tag)
+	}
+}
+
+// decodeChild reads a child reference written by encodeChild
+func decodeChild(r *bytes.Reader) (trieNode, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case childEmpty:
+		return nil, nil
+	case childValue:
+		v, err := readBytes(r)
+		return valueNode(v), err
+	case childHash:
+		h := make(hashNode, sha256.Size)
+		if _, err := io.ReadFull(r, h); err != nil {
+			return nil, err
+		}
+		return h, nil
+	default:
+		return nil, fmt.Errorf("invalid trie child kind %d", kind)
+	}
+}
+
+func (n *shortNode) copy() *shortNode { c := *n; return &c }
+func (n *fullNode) copy() *fullNode   { c := *n; return &c }
+
+func newNodeFlag() nodeFlag {
+	return nodeFlag{dirty: true}
+}
+
+// trieKey hashes a state key into its position in the trie
+func trieKey(key []byte) []byte {
+	h := sha256.Sum256(key)
+	return h[:]
+}
+
+// keyToNibbles splits key into nibbles and appends the terminator
+func keyToNibbles(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2+1)
+	for i, b := range key {
+		nibbles[i*2] = b / 16
+		nibbles[i*2+1] = b % 16
+	}
+	nibbles[len(nibbles)-1] = 16
+	return nibbles
+}
+
+// prefixLen returns the length of the common prefix of a and b
+func prefixLen(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func sha256Node(data []byte) hashNode {
+	h := sha256.Sum256(data)
+	return h[:]
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+	buf.Write(lenBuf[:n])
+	buf.Write(b)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(r.Len()) {
+		return nil, errors.New("trie node truncated")
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Key generation helpers
+func trieNodeKey(hash []byte) []byte {
+	return []byte(fmt.Sprintf("trie:node:%x", hash))
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apex/pkg/types"
+)
+
+func newTestTrie(t *testing.T) (*Database, *Trie) {
+	t.Helper()
+
+	db, err := NewMemoryDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	trie, err := NewTrie(db, types.Hash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, trie
+}
+
+// trieEntries share prefixes so that the trie has both short and full nodes
+var trieEntries = map[string]string{
+	"account:a":    "1",
+	"account:ab":   "2",
+	"account:abc":  "3",
+	"account:b":    "4",
+	"validator:a":  "5",
+	"delegation:a": "6",
+}
+
+func fillTrie(t *testing.T, trie *Trie, keys []string) {
+	t.Helper()
+
+	for _, key := range keys {
+		if err := trie.Update([]byte(key), []byte(trieEntries[key])); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTrieRootIndependentOfInsertOrder(t *testing.T) {
+	keys := []string{"account:a", "account:ab", "account:abc", "account:b", "validator:a", "delegation:a"}
+	reversed := make([]string, len(keys))
+	for i, key := range keys {
+		reversed[len(keys)-1-i] = key
+	}
+
+	_, forward := newTestTrie(t)
+	fillTrie(t, forward, keys)
+	_, backward := newTestTrie(t)
+	fillTrie(t, backward, reversed)
+
+	if forward.Hash() != backward.Hash() {
+		t.Fatalf("root %s, want %s whatever the insert order", backward.Hash().Hex(), forward.Hash().Hex())
+	}
+	if forward.Hash() == (types.Hash{}) {
+		t.Fatal("non-empty trie has the empty root")
+	}
+}
+
+func TestTrieDelete(t *testing.T) {
+	_, trie := newTestTrie(t)
+	fillTrie(t, trie, []string{"account:a", "account:ab", "account:abc", "account:b"})
+	_, without := newTestTrie(t)
+	fillTrie(t, without, []string{"account:a", "account:abc", "account:b"})
+
+	if err := trie.Delete([]byte("account:ab")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := trie.Get([]byte("account:ab")); err != nil || value != nil {
+		t.Fatalf("deleted key reads %q, %v", value, err)
+	}
+	if value, _ := trie.Get([]byte("account:abc")); !bytes.Equal(value, []byte("3")) {
+		t.Fatalf("key below the deleted one reads %q", value)
+	}
+	if trie.Hash() != without.Hash() {
+		t.Fatal("root after a delete differs from the trie that never had the key")
+	}
+
+	for _, key := range []string{"account:a", "account:abc", "account:b"} {
+		if err := trie.Delete([]byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if trie.Hash() != (types.Hash{}) {
+		t.Fatal("trie emptied by deletes does not have the empty root")
+	}
+}
+
+func TestTrieReloadFromCommittedRoot(t *testing.T) {
+	db, trie := newTestTrie(t)
+	keys := make([]string, 0, len(trieEntries))
+	for key := range trieEntries {
+		keys = append(keys, key)
+	}
+	fillTrie(t, trie, keys)
+
+	root, ops := trie.Commit()
+	if err := db.Batch(ops); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewTrie(db, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range trieEntries {
+		value, err := reloaded.Get([]byte(key))
+		if err != nil || !bytes.Equal(value, []byte(want)) {
+			t.Fatalf("%s reads %q, %v after reload, want %q", key, value, err, want)
+		}
+	}
+	if reloaded.Hash() != root {
+		t.Fatalf("reloaded root %s, want %s", reloaded.Hash().Hex(), root.Hex())
+	}
+
+	if _, err := NewTrie(db, types.Hash{1}); err == nil {
+		t.Fatal("trie opened at a root that was never committed")
+	}
+}
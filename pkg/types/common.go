@@ -34,6 +34,18 @@ const (
 	// EpochLength is blocks per epoch for validator rotation
 	EpochLength = 1200 // ~1 hour at 3s blocks
 	
+	// ValidatorSetDelay is the number of epochs between selecting a
+	// validator set and activating it
+	ValidatorSetDelay = 2
+	
+	// SignedSlotsWindow is the number of a validator's recent scheduled
+	// slots checked for downtime
+	SignedSlotsWindow = 1000
+	
+	// DowntimeJailDuration is the number of blocks (~24 hours) a validator
+	// jailed for downtime stays jailed
+	DowntimeJailDuration = 28_800
+	
 	// UnbondingPeriod in blocks (~7 days)
 	UnbondingPeriod = 201_600
 )
@@ -0,0 +1,119 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Params holds the consensus, staking and reward parameters of the chain.
+// They are stored in state, seeded from genesis.
+type Params struct {
+	BlockTime       uint64   `json:"block_time"`       // target block time in seconds
+	MaxValidators   int      `json:"max_validators"`   // size of the active validator set
+	EpochLength     uint64   `json:"epoch_length"`     // blocks per epoch
+	MinStake        *big.Int `json:"min_stake"`        // minimum validator stake
+	MinDelegation   *big.Int `json:"min_delegation"`   // minimum delegation amount
+	UnbondingPeriod uint64   `json:"unbonding_period"` // in blocks
+
+	// Epochs between selecting a validator set at the end of an epoch and
+	// activating it
+	ValidatorSetDelay uint64 `json:"validator_set_delay"`
+	// Whether validators get producer slots in proportion to voting power
+	WeightedSchedule bool `json:"weighted_schedule"`
+
+	// Scheduled slots of a validator tracked for downtime, and the share of
+	// them, in basis points, it must fill to avoid being jailed
+	SignedSlotsWindow  uint64 `json:"signed_slots_window"`
+	MinSignedPerWindow uint64 `json:"min_signed_per_window"`
+	// Blocks a validator jailed for downtime must wait before unjailing
+	DowntimeJailDuration uint64 `json:"downtime_jail_duration"`
+
+	// Largest commission change per update, in basis points
+	MaxCommissionChangeRate uint64 `json:"max_commission_change_rate"`
+
+	// Slash fractions in basis points (10000 = 100%)
+	SlashFractionDoubleSign   uint64 `json:"slash_fraction_double_sign"`
+	SlashFractionDowntime     uint64 `json:"slash_fraction_downtime"`
+	SlashFractionInvalidBlock uint64 `json:"slash_fraction_invalid_block"`
+
+	InitialReward *big.Int `json:"initial_reward"` // block reward before halvings
+	HalvingPeriod uint64   `json:"halving_period"` // blocks between halvings
+	MinimumReward *big.Int `json:"minimum_reward"` // floor of the block reward
+}
+
+// DefaultParams returns the default mainnet parameters
+func DefaultParams() *Params {
+	blocksPerYear := uint64(365 * 24 * 60 * 60 / BlockTime)
+	return &Params{
+		BlockTime:                 BlockTime,
+		MaxValidators:             MaxValidators,
+		EpochLength:               EpochLength,
+		ValidatorSetDelay:         ValidatorSetDelay,
+		WeightedSchedule:          true,
+		MinStake:                  ToWei(float64(MinStakeAmount)),
+		MinDelegation:             ToWei(10.0),
+		UnbondingPeriod:           UnbondingPeriod,
+		SignedSlotsWindow:         SignedSlotsWindow,
+		MinSignedPerWindow:        5000,
+		DowntimeJailDuration:      DowntimeJailDuration,
+		MaxCommissionChangeRate:   100,
+		SlashFractionDoubleSign:   500,
+		SlashFractionDowntime:     100,
+		SlashFractionInvalidBlock: 300,
+		InitialReward:             ToWei(2.0),
+		HalvingPeriod:             blocksPerYear * 4,
+		MinimumReward:             ToWei(0.1),
+	}
+}
+
+// Validate checks that the parameters are usable
+func (p *Params) Validate() error {
+	if p.BlockTime == 0 {
+		return errors.New("block time must be positive")
+	}
+	if p.MaxValidators <= 0 {
+		return errors.New("max validators must be positive")
+	}
+	if p.EpochLength == 0 {
+		return errors.New("epoch length must be positive")
+	}
+	if p.ValidatorSetDelay == 0 {
+		return errors.New("validator set delay must be positive")
+	}
+	if p.MinStake == nil || p.MinStake.Sign() < 0 {
+		return errors.New("invalid min stake")
+	}
+	if p.MinDelegation == nil || p.MinDelegation.Sign() < 0 {
+		return errors.New("invalid min delegation")
+	}
+	if p.SignedSlotsWindow == 0 {
+		return errors.New("signed slots window must be positive")
+	}
+	if p.MinSignedPerWindow > 10000 {
+		return errors.New("min signed per window must not exceed 100%")
+	}
+	if p.DowntimeJailDuration == 0 {
+		return errors.New("downtime jail duration must be positive")
+	}
+	if p.MaxCommissionChangeRate > 10000 {
+		return errors.New("max commission change rate must not exceed 100%")
+	}
+	if p.SlashFractionDoubleSign > 10000 || p.SlashFractionDowntime > 10000 || p.SlashFractionInvalidBlock > 10000 {
+		return errors.New("slash fractions must not exceed 100%")
+	}
+	if p.InitialReward == nil || p.InitialReward.Sign() < 0 {
+		return errors.New("invalid initial reward")
+	}
+	if p.MinimumReward == nil || p.MinimumReward.Sign() < 0 {
+		return errors.New("invalid minimum reward")
+	}
+	if p.HalvingPeriod == 0 {
+		return errors.New("halving period must be positive")
+	}
+	return nil
+}
+
+// BlocksPerYear returns the approximate number of blocks produced in a year
+func (p *Params) BlocksPerYear() uint64 {
+	return 365 * 24 * 60 * 60 / p.BlockTime
+}
@@ -1,8 +1,14 @@
 package types
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"math/big"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // ValidatorStatus represents validator state
@@ -17,19 +23,36 @@ const (
 
 // Validator represents a network validator
 type Validator struct {
-	Address           Address         `json:"address"`
-	PublicKey         []byte          `json:"public_key"`
-	VotingPower       *big.Int        `json:"voting_power"`      // Total delegated stake
-	SelfStake         *big.Int        `json:"self_stake"`        // Validator's own stake
-	Commission        uint64          `json:"commission"`        // Commission rate (basis points, 10000 = 100%)
-	Status            ValidatorStatus `json:"status"`
-	Jailed            bool            `json:"jailed"`
-	JailTime          time.Time       `json:"jail_time"`
-	MissedBlocks      uint64          `json:"missed_blocks"`
-	ProducedBlocks    uint64          `json:"produced_blocks"`
-	LastActiveEpoch   uint64          `json:"last_active_epoch"`
-	TotalRewards      *big.Int        `json:"total_rewards"`
-	CreatedAt         time.Time       `json:"created_at"`
+	Address             Address              `json:"address"`
+	PublicKey           []byte               `json:"public_key"`
+	VotingPower         *big.Int             `json:"voting_power"` // Total delegated stake
+	SelfStake           *big.Int             `json:"self_stake"`   // Validator's own stake
+	Commission          uint64               `json:"commission"`   // Commission rate (basis points, 10000 = 100%)
+	Status              ValidatorStatus      `json:"status"`
+	Jailed              bool                 `json:"jailed"`
+	JailedUntil         uint64               `json:"jailed_until"` // first block at which it may unjail
+	Tombstoned          bool                 `json:"tombstoned"`   // jailed for good for double signing
+	MissedBlocks        uint64               `json:"missed_blocks"`
+	ProducedBlocks      uint64               `json:"produced_blocks"`
+	LastActiveEpoch     uint64               `json:"last_active_epoch"`
+	TotalRewards        *big.Int             `json:"total_rewards"`
+	CreatedAt           time.Time            `json:"created_at"`
+	CommissionUpdatedAt time.Time            `json:"commission_updated_at"` // last commission change
+	Description         ValidatorDescription `json:"description"`
+}
+
+// Description length limits, in bytes
+const (
+	MaxMonikerLength = 70
+	MaxWebsiteLength = 140
+	MaxDetailsLength = 280
+)
+
+// ValidatorDescription holds the human-readable metadata of a validator
+type ValidatorDescription struct {
+	Moniker string `json:"moniker"` // display name, required
+	Website string `json:"website"` // http(s) URL, optional
+	Details string `json:"details"` // free text, optional
 }
 
 // Delegation represents a stake delegation
@@ -41,15 +64,103 @@ type Delegation struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// Vote represents a weighted vote cast by a staker for a validator
+type Vote struct {
+	Voter     Address  `json:"voter"`
+	Validator Address  `json:"validator"`
+	Weight    *big.Int `json:"weight"`
+	Height    uint64   `json:"height"` // block in which the vote was cast
+}
+
 // UnbondingDelegation represents an unbonding delegation
 type UnbondingDelegation struct {
 	Delegator       Address  `json:"delegator"`
 	Validator       Address  `json:"validator"`
 	Amount          *big.Int `json:"amount"`
+	CreationHeight  uint64   `json:"creation_height"` // block in which unbonding started
 	CompletionBlock uint64   `json:"completion_block"`
 	CreatedAt       time.Time `json:"created_at"`
 }
 
+// Redelegation represents stake moved between validators that stays liable
+// to slashing of the source validator until it completes
+type Redelegation struct {
+	Delegator       Address   `json:"delegator"`
+	ValidatorSrc    Address   `json:"validator_src"`
+	ValidatorDst    Address   `json:"validator_dst"`
+	Amount          *big.Int  `json:"amount"`
+	CreationHeight  uint64    `json:"creation_height"` // block in which the stake moved
+	CompletionBlock uint64    `json:"completion_block"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// SlashingEvent records a slash of a validator and the stake bonded to it
+type SlashingEvent struct {
+	Validator        Address  `json:"validator"`
+	Reason           string   `json:"reason"`
+	Fraction         uint64   `json:"fraction"`          // basis points
+	Amount           *big.Int `json:"amount"`            // total stake burned
+	InfractionHeight uint64   `json:"infraction_height"` // block of the offence
+	Height           uint64   `json:"height"`            // block that applied the slash
+}
+
+// ValidatorSet is the set of validators scheduled to produce the blocks of
+// an epoch, with what is needed to derive its producer schedule and to weigh
+// its members' precommits
+type ValidatorSet struct {
+	Epoch      uint64     `json:"epoch"`
+	Validators []Address  `json:"validators"`
+	Powers     []*big.Int `json:"powers"`            // voting power of each member when selected
+	Weights    []*big.Int `json:"weights,omitempty"` // slot weights; equal if empty
+	Seed       Hash       `json:"seed"`              // shuffles the producer schedule
+}
+
+// SigningInfo tracks which of the last SignedSlotsWindow slots scheduled
+// for a validator it missed. Bit i of MissedBitmap holds the slot recorded
+// at an index congruent to i modulo the window.
+type SigningInfo struct {
+	Address      Address `json:"address"`
+	IndexOffset  uint64  `json:"index_offset"`  // scheduled slots recorded so far
+	MissedCount  uint64  `json:"missed_count"`  // missed slots in the window
+	MissedBitmap []byte  `json:"missed_bitmap"` // one bit per slot of the window
+}
+
+// NewSigningInfo creates an empty signing window
+func NewSigningInfo(addr Address) *SigningInfo {
+	return &SigningInfo{Address: addr}
+}
+
+// Record records whether the next scheduled slot was missed, overwriting
+// the slot that leaves the window. A window of a different size starts over.
+func (s *SigningInfo) Record(missed bool, window uint64) {
+	if uint64(len(s.MissedBitmap)) != (window+7)/8 {
+		s.IndexOffset = 0
+		s.MissedCount = 0
+		s.MissedBitmap = make([]byte, (window+7)/8)
+	}
+	
+	index := s.IndexOffset % window
+	mask := byte(1) << (index % 8)
+	wasMissed := s.MissedBitmap[index/8]&mask != 0
+	switch {
+	case missed && !wasMissed:
+		s.MissedBitmap[index/8] |= mask
+		s.MissedCount++
+	case !missed && wasMissed:
+		s.MissedBitmap[index/8] &^= mask
+		s.MissedCount--
+	}
+	s.IndexOffset++
+}
+
+// Recorded returns the number of slots in the window so far
+func (s *SigningInfo) Recorded(window uint64) uint64 {
+	if s.IndexOffset < window {
+		return s.IndexOffset
+	}
+	return window
+}
+
 // NewValidator creates a new validator
 func NewValidator(addr Address, pubKey []byte, selfStake *big.Int, commission uint64) *Validator {
 	return &Validator{
@@ -67,14 +178,85 @@ func NewValidator(addr Address, pubKey []byte, selfStake *big.Int, commission ui
 	}
 }
 
+// Validate checks the lengths and characters of the description
+func (d ValidatorDescription) Validate() error {
+	if strings.TrimSpace(d.Moniker) == "" {
+		return errors.New("moniker is required")
+	}
+	if len(d.Moniker) > MaxMonikerLength {
+		return fmt.Errorf("moniker longer than %d bytes", MaxMonikerLength)
+	}
+	if len(d.Website) > MaxWebsiteLength {
+		return fmt.Errorf("website longer than %d bytes", MaxWebsiteLength)
+	}
+	if len(d.Details) > MaxDetailsLength {
+		return fmt.Errorf("details longer than %d bytes", MaxDetailsLength)
+	}
+	
+	if !isPrintable(d.Moniker, false) {
+		return errors.New("moniker contains invalid characters")
+	}
+	if !isPrintable(d.Details, true) {
+		return errors.New("details contain invalid characters")
+	}
+	if d.Website != "" {
+		if !isPrintable(d.Website, false) || strings.ContainsRune(d.Website, ' ') ||
+			!(strings.HasPrefix(d.Website, "https://") || strings.HasPrefix(d.Website, "http://")) {
+			return errors.New("website must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// isPrintable reports whether s is valid UTF-8 without control characters,
+// optionally allowing newlines
+func isPrintable(s string, allowNewlines bool) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r == '\n' && allowNewlines {
+			continue
+		}
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
 // IsActive returns true if validator is active
 func (v *Validator) IsActive() bool {
 	return v.Status == ValidatorStatusActive && !v.Jailed
 }
 
-// CanProduceBlocks returns true if validator can produce blocks
-func (v *Validator) CanProduceBlocks() bool {
-	return v.IsActive() && v.VotingPower.Cmp(ToWei(float64(MinStakeAmount))) >= 0
+// Jail jails the validator until block until. A longer jail already being
+// served is kept.
+func (v *Validator) Jail(until uint64) {
+	v.Jailed = true
+	v.Status = ValidatorStatusJailed
+	if until > v.JailedUntil {
+		v.JailedUntil = until
+	}
+}
+
+// Tombstone jails the validator permanently
+func (v *Validator) Tombstone() {
+	v.Jail(math.MaxUint64)
+	v.Tombstoned = true
+}
+
+// Unjail releases the validator from jail
+func (v *Validator) Unjail() {
+	v.Jailed = false
+	v.Status = ValidatorStatusActive
+	v.JailedUntil = 0
+	v.MissedBlocks = 0
+}
+
+// CanProduceBlocks returns true if validator is active with at least minStake
+func (v *Validator) CanProduceBlocks(minStake *big.Int) bool {
+	return v.IsActive() && v.VotingPower.Cmp(minStake) >= 0
 }
 
 // AddVotingPower adds to validator's voting power